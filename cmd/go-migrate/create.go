@@ -0,0 +1,124 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/Borislavv/go-migrate/pkg/migrate/generator"
+	"github.com/Borislavv/go-migrate/pkg/migrate/storage/clickhouse"
+	"github.com/Borislavv/go-migrate/pkg/migrate/storage/mongo"
+	"github.com/Borislavv/go-migrate/pkg/migrate/storage/mysql"
+	"github.com/Borislavv/go-migrate/pkg/migrate/storage/postgres"
+	"github.com/Borislavv/go-migrate/pkg/migrate/storage/redis"
+	"github.com/Borislavv/go-migrate/pkg/migrate/storage/sqlite"
+	"path/filepath"
+)
+
+// create writes a pair of up/down migration files into the storage migrations directory
+// ({storage}-path flag or the instance path joined with *_MIGRATIONS_DIR) or into the explicitly given one.
+func create(args []string, opts *options) error {
+	flags := flag.NewFlagSet("create", flag.ContinueOnError)
+	storageName := flags.String("storage", "", "target storage (clickhouse, mongodb, mysql, postgres, redis, sqlite) or instance name which defines the directory and extension")
+	dir := flags.String("dir", "", "target migrations directory, overrides the storage one")
+	ext := flags.String("ext", "", "migration files extension, overrides the storage one (default sql)")
+	format := flags.String("format", string(generator.Sequential), "version format: sequential or timestamp")
+	version := flags.Uint("version", 0, "explicit version, overrides the format")
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidArgs, err)
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("%w: expected a single migration name", ErrInvalidArgs)
+	}
+
+	cfg := generator.Config{Dir: *dir, Ext: *ext, Format: generator.Format(*format)}
+	if *storageName != "" {
+		storageDir, storageExt, err := storageMigrations(*storageName, opts)
+		if err != nil {
+			return err
+		}
+		if cfg.Dir == "" {
+			cfg.Dir = storageDir
+		}
+		if cfg.Ext == "" {
+			cfg.Ext = storageExt
+		}
+	}
+	if cfg.Dir == "" {
+		return fmt.Errorf("%w: either -storage or -dir is required", ErrInvalidArgs)
+	}
+
+	g := generator.New(cfg)
+
+	var (
+		up, down string
+		err      error
+	)
+	if *version > 0 {
+		up, down, err = g.CreateVersion(*version, flags.Arg(0))
+	} else {
+		up, down, err = g.Create(flags.Arg(0))
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(up)
+	fmt.Println(down)
+	return nil
+}
+
+// storageMigrations returns the migrations directory and files extension of the storage or named instance.
+func storageMigrations(name string, opts *options) (dir, ext string, err error) {
+	driver, prefix, path := name, "", ""
+	if instance, ok := opts.instances.get(name); ok {
+		driver, prefix, path = instance.Driver, instance.EnvPrefix(), instance.path
+	}
+
+	switch driver {
+	case clickhouse.DriverName:
+		cfg, err := clickhouse.LoadWithPrefix(prefix)
+		if err != nil {
+			return "", "", err
+		}
+		return filepath.Join(pathOr(path, opts.clickhousePath), cfg.GetClickHouseMigrationsDir()), clickhouse.MigrationsExt, nil
+	case mongo.DriverName:
+		cfg, err := mongo.LoadWithPrefix(prefix)
+		if err != nil {
+			return "", "", err
+		}
+		return filepath.Join(pathOr(path, opts.mongoPath), cfg.GetMongoMigrationsDir()), mongo.MigrationsExt, nil
+	case mysql.DriverName:
+		cfg, err := mysql.LoadWithPrefix(prefix)
+		if err != nil {
+			return "", "", err
+		}
+		return filepath.Join(pathOr(path, opts.mysqlPath), cfg.GetMySQLMigrationsDir()), mysql.MigrationsExt, nil
+	case postgres.DriverName:
+		cfg, err := postgres.LoadWithPrefix(prefix)
+		if err != nil {
+			return "", "", err
+		}
+		return filepath.Join(pathOr(path, opts.postgresPath), cfg.GetPostgresMigrationsDir()), postgres.MigrationsExt, nil
+	case redis.DriverName:
+		cfg, err := redis.LoadWithPrefix(prefix)
+		if err != nil {
+			return "", "", err
+		}
+		return filepath.Join(pathOr(path, opts.redisPath), cfg.GetRedisMigrationsDir()), redis.MigrationsExt, nil
+	case sqlite.DriverName:
+		cfg, err := sqlite.LoadWithPrefix(prefix)
+		if err != nil {
+			return "", "", err
+		}
+		return filepath.Join(pathOr(path, opts.sqlitePath), cfg.GetSQLiteMigrationsDir()), sqlite.MigrationsExt, nil
+	default:
+		return "", "", fmt.Errorf("%w: unknown storage %s", ErrInvalidArgs, name)
+	}
+}
+
+// pathOr returns the instance path when it's set, otherwise the driver one.
+func pathOr(path, driverPath string) string {
+	if path != "" {
+		return path
+	}
+	return driverPath
+}
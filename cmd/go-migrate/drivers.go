@@ -0,0 +1,11 @@
+package main
+
+// The CLI migrates each built-in storage, so all drivers are registered.
+import (
+	_ "github.com/Borislavv/go-migrate/pkg/migrate/storage/clickhouse"
+	_ "github.com/Borislavv/go-migrate/pkg/migrate/storage/mongo"
+	_ "github.com/Borislavv/go-migrate/pkg/migrate/storage/mysql"
+	_ "github.com/Borislavv/go-migrate/pkg/migrate/storage/postgres"
+	_ "github.com/Borislavv/go-migrate/pkg/migrate/storage/redis"
+	_ "github.com/Borislavv/go-migrate/pkg/migrate/storage/sqlite"
+)
@@ -0,0 +1,391 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/Borislavv/go-logger/pkg/logger"
+	loggerenum "github.com/Borislavv/go-logger/pkg/logger/enum"
+	"github.com/Borislavv/go-migrate/pkg/migrate"
+	"github.com/Borislavv/go-migrate/pkg/migrate/storage"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+const usage = `Usage: go-migrate [flags] <command> [args]
+
+Commands:
+  up                     apply all pending migrations
+  down                   roll back all applied migrations (subject to the down policy)
+  goto <version>         migrate up or down to the given version
+  steps <n>              apply n migrations (n > 0) or roll back |n| migrations (n < 0)
+  force <version>        set the version without running migrations (requires a single storage)
+  version                print the current version of each storage
+  status                 print applied and pending migrations of each storage as JSON
+  plan [up|down]         print migration files which would be executed as JSON (default: up)
+  history                print executed migrations of each storage as JSON
+  verify                 print applied migrations which files were changed as JSON (see MIGRATIONS_CHECKSUM_POLICY)
+  gaps                   print out-of-order and missing migrations of each storage as JSON
+  repair [policy]        report or repair dirty storages as JSON, policy: report, force or down (default: MIGRATIONS_REPAIR_POLICY)
+  create [flags] <name>  create a pair of up/down migration files (see create -h)
+
+Storages are configured by the CLICKHOUSE_*, MONGO_*, MYSQL_*, POSTGRES_*, REDIS_* and SQLITE_* env variables,
+named instances by the same ones prefixed with the upper-cased instance name (e.g. BILLING_POSTGRES_*).
+
+Flags:
+`
+
+var (
+	ErrUnknownCommand = errors.New("unknown command")
+	ErrInvalidArgs    = errors.New("invalid command arguments")
+)
+
+type options struct {
+	storages       string
+	clickhousePath string
+	mongoPath      string
+	mysqlPath      string
+	postgresPath   string
+	redisPath      string
+	sqlitePath     string
+	instances      instances
+	confirm        string
+}
+
+// action runs a parsed command against the migrator.
+type action func(ctx context.Context, lgr logger.Logger, migrator *migrate.Migrate) error
+
+func main() {
+	if err := run(); err != nil {
+		// errors which occurred after the logger was initialized are already logged
+		if errors.Is(err, ErrUnknownCommand) || errors.Is(err, ErrInvalidArgs) {
+			_, _ = fmt.Fprintln(os.Stderr, err.Error())
+			flag.Usage()
+			os.Exit(2)
+		}
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	opts := &options{}
+	flag.StringVar(&opts.storages, "storages", "", "comma separated list of storages to use (clickhouse, mongodb, mysql, postgres, redis, sqlite), all enabled by default")
+	flag.StringVar(&opts.clickhousePath, "clickhouse-path", ".", "path to the directory which contains the ClickHouse migrations directory")
+	flag.StringVar(&opts.mongoPath, "mongodb-path", ".", "path to the directory which contains the MongoDB migrations directory")
+	flag.StringVar(&opts.mysqlPath, "mysql-path", ".", "path to the directory which contains the MySQL migrations directory")
+	flag.StringVar(&opts.postgresPath, "postgres-path", ".", "path to the directory which contains the PostgreSQL migrations directory")
+	flag.StringVar(&opts.redisPath, "redis-path", ".", "path to the directory which contains the Redis migrations directory")
+	flag.StringVar(&opts.sqlitePath, "sqlite-path", ".", "path to the directory which contains the SQLite migrations directory")
+	flag.Var(&opts.instances, "instance", "named storage instance as name=driver:path, configured by the NAME_ prefixed env (repeatable)")
+	flag.StringVar(&opts.confirm, "confirm", "", "confirmation token for down migrations and repairs (see MIGRATIONS_DOWN_CONFIRMATION_TOKEN)")
+	flag.Usage = func() {
+		_, _ = fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		return fmt.Errorf("%w: command is required", ErrUnknownCommand)
+	}
+	command, args := flag.Arg(0), flag.Args()[1:]
+
+	if command == "create" {
+		err := create(args, opts)
+		if err != nil && !errors.Is(err, ErrInvalidArgs) {
+			_, _ = fmt.Fprintln(os.Stderr, err.Error())
+		}
+		return err
+	}
+
+	act, err := parse(command, args, opts)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	output, cancelOutput, err := logger.NewOutput(loggerenum.Stderr)
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, "logger: unable to initialize output: "+err.Error())
+		return err
+	}
+	defer cancelOutput()
+
+	lgr, cancelLogger, err := logger.NewLogrus(output)
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, "logger: unable to initialize logrus: "+err.Error())
+		return err
+	}
+	defer cancelLogger()
+
+	factoryOpts := append([]storage.Option{storage.Filesystems{
+		storage.ClickHouse: os.DirFS(opts.clickhousePath),
+		storage.MongoDB:    os.DirFS(opts.mongoPath),
+		storage.MySQL:      os.DirFS(opts.mysqlPath),
+		storage.PostgreSQL: os.DirFS(opts.postgresPath),
+		storage.Redis:      os.DirFS(opts.redisPath),
+		storage.SQLite:     os.DirFS(opts.sqlitePath),
+	}}, opts.instances.options()...)
+	// the storages which are not selected are never made, so they don't connect
+	factoryOpts = append(factoryOpts, storage.Only(storageNames(opts.storages)...))
+
+	migrator, err := migrate.New(ctx, lgr, storage.NewFactory(lgr, factoryOpts...))
+	if err != nil {
+		return err
+	}
+
+	return act(ctx, lgr, migrator)
+}
+
+// parse validates the command arguments before any storage connection is made.
+func parse(command string, args []string, opts *options) (action, error) {
+	switch command {
+	case "up":
+		return func(ctx context.Context, _ logger.Logger, migrator *migrate.Migrate) error {
+			return migrator.Up(ctx)
+		}, nil
+	case "down":
+		return func(ctx context.Context, _ logger.Logger, migrator *migrate.Migrate) error {
+			if opts.confirm != "" {
+				migrator.DownPolicy().Confirmation = opts.confirm
+			}
+			return migrator.Down(ctx)
+		}, nil
+	case "goto":
+		version, err := uintArg(args)
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx context.Context, _ logger.Logger, migrator *migrate.Migrate) error {
+			if opts.confirm != "" {
+				migrator.DownPolicy().Confirmation = opts.confirm
+			}
+			for _, s := range migrator.Storages() {
+				if err := migrator.Migrate(ctx, version, s); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, nil
+	case "steps":
+		n, err := intArg(args)
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx context.Context, _ logger.Logger, migrator *migrate.Migrate) error {
+			if opts.confirm != "" {
+				migrator.DownPolicy().Confirmation = opts.confirm
+			}
+			for _, s := range migrator.Storages() {
+				if err := migrator.Steps(ctx, n, s); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, nil
+	case "force":
+		n, err := intArg(args)
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx context.Context, lgr logger.Logger, migrator *migrate.Migrate) error {
+			if len(migrator.Storages()) != 1 {
+				return lgr.Error(ctx, fmt.Errorf("%w: force requires exactly one storage, use -storages", ErrInvalidArgs), nil)
+			}
+			return migrator.Force(ctx, n, migrator.Storages()[0])
+		}, nil
+	case "version":
+		return func(ctx context.Context, _ logger.Logger, migrator *migrate.Migrate) error {
+			for _, s := range migrator.Storages() {
+				version, dirty, err := migrator.Version(ctx, s)
+				if err != nil {
+					return err
+				}
+				fmt.Printf("%s: version=%d dirty=%t\n", s.Name(), version, dirty)
+			}
+			return nil
+		}, nil
+	case "status":
+		return func(ctx context.Context, lgr logger.Logger, migrator *migrate.Migrate) error {
+			report, err := migrator.Status(ctx)
+			if err != nil {
+				return err
+			}
+			return printJSON(ctx, lgr, report)
+		}, nil
+	case "history":
+		return func(ctx context.Context, lgr logger.Logger, migrator *migrate.Migrate) error {
+			type history struct {
+				Storage string                 `json:"storage"`
+				Entries []storage.HistoryEntry `json:"entries"`
+			}
+
+			histories := make([]history, 0, len(migrator.Storages()))
+			for _, s := range migrator.Storages() {
+				entries, err := migrator.History(ctx, s)
+				if err != nil {
+					return err
+				}
+				histories = append(histories, history{Storage: s.Name(), Entries: entries})
+			}
+			return printJSON(ctx, lgr, histories)
+		}, nil
+	case "verify":
+		return func(ctx context.Context, lgr logger.Logger, migrator *migrate.Migrate) error {
+			mismatches, err := migrator.Verify(ctx)
+			if mismatches == nil {
+				mismatches = []migrate.ChecksumMismatch{}
+			}
+			if printErr := printJSON(ctx, lgr, mismatches); printErr != nil {
+				return printErr
+			}
+			return err
+		}, nil
+	case "gaps":
+		return func(ctx context.Context, lgr logger.Logger, migrator *migrate.Migrate) error {
+			gaps, err := migrator.Gaps(ctx)
+			if err != nil {
+				return err
+			}
+			return printJSON(ctx, lgr, gaps)
+		}, nil
+	case "repair":
+		var policy migrate.RepairPolicy
+		if len(args) > 0 {
+			policy = migrate.RepairPolicy(args[0])
+		}
+		if policy != "" && policy != migrate.RepairReport && policy != migrate.RepairForce && policy != migrate.RepairDown {
+			return nil, fmt.Errorf("%w: repair policy must be report, force or down", ErrInvalidArgs)
+		}
+		return func(ctx context.Context, lgr logger.Logger, migrator *migrate.Migrate) error {
+			if policy != "" {
+				if err := migrator.SetRepairPolicy(policy); err != nil {
+					return err
+				}
+			}
+			if opts.confirm != "" {
+				migrator.DownPolicy().Confirmation = opts.confirm
+			}
+			repairs, err := migrator.Repair(ctx)
+			if printErr := printJSON(ctx, lgr, repairs); printErr != nil {
+				return printErr
+			}
+			return err
+		}, nil
+	case "plan":
+		direction := migrate.Up
+		if len(args) > 0 {
+			direction = migrate.Direction(args[0])
+		}
+		if direction != migrate.Up && direction != migrate.Down {
+			return nil, fmt.Errorf("%w: plan direction must be up or down", ErrInvalidArgs)
+		}
+		return func(ctx context.Context, lgr logger.Logger, migrator *migrate.Migrate) error {
+			plan, err := migrator.Plan(ctx, direction)
+			if err != nil {
+				return err
+			}
+			return printJSON(ctx, lgr, plan)
+		}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownCommand, command)
+	}
+}
+
+func uintArg(args []string) (uint, error) {
+	if len(args) != 1 {
+		return 0, fmt.Errorf("%w: expected a single version argument", ErrInvalidArgs)
+	}
+	v, err := strconv.ParseUint(args[0], 10, 0)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrInvalidArgs, err)
+	}
+	return uint(v), nil
+}
+
+func intArg(args []string) (int, error) {
+	if len(args) != 1 {
+		return 0, fmt.Errorf("%w: expected a single numeric argument", ErrInvalidArgs)
+	}
+	v, err := strconv.Atoi(args[0])
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrInvalidArgs, err)
+	}
+	return v, nil
+}
+
+func printJSON(ctx context.Context, lgr logger.Logger, v any) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		return lgr.Error(ctx, errors.New("failed to encode output"), logger.Fields{"err": err.Error()})
+	}
+	return nil
+}
+
+// storageNames splits the comma separated -storages flag, nothing selected means all storages.
+func storageNames(value string) []string {
+	var names []string
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// instance is a named storage instance given by the -instance flag.
+type instance struct {
+	storage.Instance
+	path string
+}
+
+// instances implements flag.Value for the repeatable -instance name=driver:path flag.
+type instances []instance
+
+func (i *instances) String() string {
+	values := make([]string, 0, len(*i))
+	for _, inst := range *i {
+		values = append(values, inst.Name+"="+inst.Driver+":"+inst.path)
+	}
+	return strings.Join(values, ",")
+}
+
+func (i *instances) Set(value string) error {
+	name, spec, ok := strings.Cut(value, "=")
+	if !ok || name == "" {
+		return errors.New("expected name=driver:path")
+	}
+	driver, path, _ := strings.Cut(spec, ":")
+	if driver == "" {
+		return errors.New("expected name=driver:path")
+	}
+	if path == "" {
+		path = "."
+	}
+	*i = append(*i, instance{Instance: storage.Instance{Name: name, Driver: driver, FS: os.DirFS(path)}, path: path})
+	return nil
+}
+
+func (i *instances) get(name string) (instance, bool) {
+	for _, inst := range *i {
+		if inst.Name == name {
+			return inst, true
+		}
+	}
+	return instance{}, false
+}
+
+func (i *instances) options() []storage.Option {
+	result := make([]storage.Option, 0, len(*i))
+	for _, inst := range *i {
+		result = append(result, inst.Instance)
+	}
+	return result
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		command string
+		args    []string
+		err     error
+	}{
+		{command: "up"},
+		{command: "down"},
+		{command: "goto", args: []string{"7"}},
+		{command: "goto", err: ErrInvalidArgs},
+		{command: "goto", args: []string{"-1"}, err: ErrInvalidArgs},
+		{command: "steps", args: []string{"-2"}},
+		{command: "steps", args: []string{"1", "2"}, err: ErrInvalidArgs},
+		{command: "force", args: []string{"-1"}},
+		{command: "force", args: []string{"x"}, err: ErrInvalidArgs},
+		{command: "version"},
+		{command: "status"},
+		{command: "history"},
+		{command: "verify"},
+		{command: "gaps"},
+		{command: "repair"},
+		{command: "repair", args: []string{"down"}},
+		{command: "repair", args: []string{"drop"}, err: ErrInvalidArgs},
+		{command: "plan"},
+		{command: "plan", args: []string{"down"}},
+		{command: "plan", args: []string{"sideways"}, err: ErrInvalidArgs},
+		{command: "migrate", err: ErrUnknownCommand},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			act, err := parse(tt.command, tt.args, &options{})
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected %v, got %v", tt.err, err)
+			}
+			if (act == nil) != (tt.err != nil) {
+				t.Fatalf("unexpected action of %s %v", tt.command, tt.args)
+			}
+		})
+	}
+}
+
+func TestIntArg(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected int
+		err      error
+	}{
+		{args: []string{"3"}, expected: 3},
+		{args: []string{"-3"}, expected: -3},
+		{args: []string{"three"}, err: ErrInvalidArgs},
+		{err: ErrInvalidArgs},
+		{args: []string{"1", "2"}, err: ErrInvalidArgs},
+	}
+
+	for _, tt := range tests {
+		actual, err := intArg(tt.args)
+		if !errors.Is(err, tt.err) || actual != tt.expected {
+			t.Fatalf("unexpected result of %v: %d, %v", tt.args, actual, err)
+		}
+	}
+}
+
+func TestUintArg(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected uint
+		err      error
+	}{
+		{args: []string{"20240501"}, expected: 20240501},
+		{args: []string{"0"}},
+		{args: []string{"-1"}, err: ErrInvalidArgs},
+		{err: ErrInvalidArgs},
+		{args: []string{"1", "2"}, err: ErrInvalidArgs},
+	}
+
+	for _, tt := range tests {
+		actual, err := uintArg(tt.args)
+		if !errors.Is(err, tt.err) || actual != tt.expected {
+			t.Fatalf("unexpected result of %v: %d, %v", tt.args, actual, err)
+		}
+	}
+}
+
+func TestInstances_Set(t *testing.T) {
+	tests := []struct {
+		value  string
+		name   string
+		driver string
+		path   string
+		fail   bool
+	}{
+		{value: "billing=postgres:./db/billing", name: "billing", driver: "postgres", path: "./db/billing"},
+		{value: "users=mysql", name: "users", driver: "mysql", path: "."},
+		{value: "events=clickhouse:", name: "events", driver: "clickhouse", path: "."},
+		{value: "billing", fail: true},
+		{value: "=postgres:.", fail: true},
+		{value: "billing=:./db", fail: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			var i instances
+			err := i.Set(tt.value)
+			if (err != nil) != tt.fail {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.fail {
+				return
+			}
+
+			inst, ok := i.get(tt.name)
+			if !ok || inst.Driver != tt.driver || inst.path != tt.path || inst.FS == nil {
+				t.Fatalf("unexpected instance: %+v", inst)
+			}
+			if len(i.options()) != 1 || i.String() != tt.name+"="+tt.driver+":"+tt.path {
+				t.Fatalf("unexpected instances: %s", i.String())
+			}
+		})
+	}
+}
+
+func TestStorageNames(t *testing.T) {
+	tests := map[string][]string{
+		"":                        nil,
+		"postgres":                {"postgres"},
+		" postgres , billing ,, ": {"postgres", "billing"},
+		"mongodb,redis,sqlite":    {"mongodb", "redis", "sqlite"},
+	}
+
+	for value, expected := range tests {
+		if actual := storageNames(value); !slices.Equal(actual, expected) {
+			t.Fatalf("unexpected names of %q: %v", value, actual)
+		}
+	}
+}
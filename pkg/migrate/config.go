@@ -0,0 +1,41 @@
+package migrate
+
+import (
+	"errors"
+	"github.com/kelseyhightower/envconfig"
+	"time"
+)
+
+var ErrFailedLoadConfig = errors.New("failed to load migrate config")
+
+type Config struct {
+	// Timeout limits each Migrate call across all storages (unlimited when zero),
+	// per-storage limits are configured by drivers (e.g. POSTGRES_MIGRATIONS_TIMEOUT).
+	Timeout time.Duration `envconfig:"MIGRATIONS_TIMEOUT" default:"0"`
+	// LockEnabled makes Up, Down, Migrate, Steps and Force acquire the distributed lock of each storage
+	// which supports it (see storage.Locker), so only one replica migrates at a time.
+	LockEnabled bool `envconfig:"MIGRATIONS_LOCK_ENABLED" default:"false"`
+	// LockKey identifies the lock, it's suffixed by the storage name.
+	LockKey string `envconfig:"MIGRATIONS_LOCK_KEY" default:"go-migrate"`
+	// LockTimeout limits waiting for the lock held by another replica (unlimited when zero).
+	LockTimeout time.Duration `envconfig:"MIGRATIONS_LOCK_TIMEOUT" default:"5m"`
+	// Dependencies orders storages as a comma separated list of storage[@version]:dependsOn[@version],
+	// e.g. "mongodb@5:postgres@12,redis:postgres" (see Dependency).
+	Dependencies []string `envconfig:"MIGRATIONS_DEPENDENCIES"`
+	// AppVersion is recorded into the migrations history (e.g. the build tag or commit).
+	AppVersion string `envconfig:"MIGRATIONS_APP_VERSION"`
+	// ChecksumPolicy is applied when an applied migration file was changed: fail, warn or ignore (see Verify).
+	ChecksumPolicy ChecksumPolicy `envconfig:"MIGRATIONS_CHECKSUM_POLICY" default:"fail"`
+	// AllowOutOfOrder makes Up apply never applied migrations below the current version instead of reporting them.
+	AllowOutOfOrder bool `envconfig:"MIGRATIONS_ALLOW_OUT_OF_ORDER" default:"false"`
+	// RepairPolicy defines what Repair does with dirty storages: report, force or down (see RepairPolicy).
+	RepairPolicy RepairPolicy `envconfig:"MIGRATIONS_REPAIR_POLICY" default:"report"`
+}
+
+func LoadConfig() (*Config, error) {
+	cfg := new(Config)
+	if err := envconfig.Process("", cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
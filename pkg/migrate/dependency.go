@@ -0,0 +1,339 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Borislavv/go-migrate/pkg/migrate/storage"
+	"github.com/Borislavv/migrate/v4"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrInvalidDependency = errors.New("invalid storage dependency")
+	ErrDependencyCycle   = errors.New("storage dependencies contain a cycle")
+	ErrDependencyFailed  = errors.New("storage dependency failed")
+)
+
+// Dependency makes Storage wait for DependsOn while migrating up and DependsOn wait for Storage while migrating down.
+// Without versions Storage starts once DependsOn is fully migrated up. Version delays only the Storage migrations
+// starting from that version and DependsOnVersion is enough to be reached by DependsOn instead of the full migration.
+// Versions are ignored by Down which always waits for the whole dependent storage.
+type Dependency struct {
+	Storage          string
+	Version          uint
+	DependsOn        string
+	DependsOnVersion uint
+}
+
+// ParseDependency parses the "storage[@version]:dependsOn[@version]" form, e.g. "mongodb@5:postgres@12".
+func ParseDependency(s string) (Dependency, error) {
+	left, right, ok := strings.Cut(strings.TrimSpace(s), ":")
+	if !ok {
+		return Dependency{}, fmt.Errorf("%w: %q, expected storage[@version]:dependsOn[@version]", ErrInvalidDependency, s)
+	}
+
+	var (
+		d   Dependency
+		err error
+	)
+	if d.Storage, d.Version, err = parseDependencyNode(left); err != nil {
+		return Dependency{}, fmt.Errorf("%w: %q: %w", ErrInvalidDependency, s, err)
+	}
+	if d.DependsOn, d.DependsOnVersion, err = parseDependencyNode(right); err != nil {
+		return Dependency{}, fmt.Errorf("%w: %q: %w", ErrInvalidDependency, s, err)
+	}
+	return d, nil
+}
+
+func parseDependencyNode(s string) (name string, version uint, err error) {
+	name, v, ok := strings.Cut(s, "@")
+	if name == "" {
+		return "", 0, errors.New("storage name is empty")
+	}
+	if !ok {
+		return name, 0, nil
+	}
+	n, err := strconv.ParseUint(v, 10, 0)
+	if err != nil {
+		return "", 0, err
+	}
+	return name, uint(n), nil
+}
+
+func (d Dependency) String() string {
+	node := func(name string, version uint) string {
+		if version == 0 {
+			return name
+		}
+		return name + "@" + strconv.FormatUint(uint64(version), 10)
+	}
+	return node(d.Storage, d.Version) + ":" + node(d.DependsOn, d.DependsOnVersion)
+}
+
+// SetDependencies replaces the dependencies loaded from env (MIGRATIONS_DEPENDENCIES) and orders Storages
+// topologically, so sequential callers follow the dependencies too. Dependencies on storages which are not
+// configured (e.g. disabled) are ignored.
+func (m *Migrate) SetDependencies(deps ...Dependency) error {
+	names := make(map[string]struct{}, len(m.storages))
+	for _, s := range m.storages {
+		names[s.Name()] = struct{}{}
+	}
+
+	known := make([]Dependency, 0, len(deps))
+	for _, d := range deps {
+		if d.Storage == d.DependsOn {
+			return fmt.Errorf("%w: %s depends on itself", ErrInvalidDependency, d)
+		}
+		_, ok1 := names[d.Storage]
+		_, ok2 := names[d.DependsOn]
+		if ok1 && ok2 {
+			known = append(known, d)
+		}
+	}
+
+	ordered, err := topologicalOrder(m.storages, known)
+	if err != nil {
+		return err
+	}
+
+	m.storages, m.dependencies = ordered, known
+	return nil
+}
+
+// Dependencies returns the current storage dependencies.
+func (m *Migrate) Dependencies() []Dependency {
+	return m.dependencies
+}
+
+// topologicalOrder sorts storages so each one follows its dependencies keeping the original order otherwise.
+func topologicalOrder(storages []storage.Storager, deps []Dependency) ([]storage.Storager, error) {
+	indegree := make(map[string]int, len(storages))
+	dependents := make(map[string][]string, len(storages))
+	for _, d := range deps {
+		indegree[d.Storage]++
+		dependents[d.DependsOn] = append(dependents[d.DependsOn], d.Storage)
+	}
+
+	ordered := make([]storage.Storager, 0, len(storages))
+	visited := make([]bool, len(storages))
+	for len(ordered) < len(storages) {
+		progressed := false
+		for i, s := range storages {
+			if visited[i] || indegree[s.Name()] > 0 {
+				continue
+			}
+			visited[i] = true
+			ordered = append(ordered, s)
+			for _, dependent := range dependents[s.Name()] {
+				indegree[dependent]--
+			}
+			progressed = true
+		}
+		if !progressed {
+			cycle := make([]string, 0, len(storages))
+			for i, s := range storages {
+				if !visited[i] {
+					cycle = append(cycle, s.Name())
+				}
+			}
+			return nil, fmt.Errorf("%w: %s", ErrDependencyCycle, strings.Join(cycle, ", "))
+		}
+	}
+
+	return ordered, nil
+}
+
+// progress tracks versions reached and storages finished while the dependencies are scheduled.
+type progress struct {
+	mu       sync.Mutex
+	changed  chan struct{}
+	versions map[string]uint
+	finished map[string]error
+}
+
+func newProgress() *progress {
+	return &progress{
+		changed:  make(chan struct{}),
+		versions: make(map[string]uint),
+		finished: make(map[string]error),
+	}
+}
+
+func (p *progress) reach(name string, version uint) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if version > p.versions[name] {
+		p.versions[name] = version
+	}
+	p.notify()
+}
+
+func (p *progress) finish(name string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.finished[name] = err
+	p.notify()
+}
+
+// notify wakes up all waiters, must be called under the lock.
+func (p *progress) notify() {
+	close(p.changed)
+	p.changed = make(chan struct{})
+}
+
+// wait blocks until the storage reaches the version (or finishes when version is zero).
+func (p *progress) wait(ctx context.Context, name string, version uint) error {
+	for {
+		p.mu.Lock()
+		err, finished := p.finished[name]
+		reached := version > 0 && p.versions[name] >= version
+		changed := p.changed
+		p.mu.Unlock()
+
+		switch {
+		case finished && err != nil:
+			return fmt.Errorf("%w: %s", ErrDependencyFailed, name)
+		case finished || reached:
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// checkpoint is a version where the storage stops while migrating up to publish its progress
+// and to wait for its own dependencies.
+type checkpoint struct {
+	version uint
+	// publish is the greatest version others depend on which is satisfied once the checkpoint is reached.
+	publish uint
+	waits   []Dependency
+}
+
+// checkpoints returns the stops of the storage ordered by version, the zero version means before the first migration.
+func (m *Migrate) checkpoints(s storage.Storager) ([]checkpoint, error) {
+	var available []uint
+	byVersion := make(map[uint]*checkpoint)
+	at := func(version uint) *checkpoint {
+		if c, ok := byVersion[version]; ok {
+			return c
+		}
+		c := &checkpoint{version: version}
+		byVersion[version] = c
+		return c
+	}
+
+	for _, d := range m.dependencies {
+		if d.Storage != s.Name() && (d.DependsOn != s.Name() || d.DependsOnVersion == 0) {
+			continue
+		}
+		if available == nil {
+			migrations, err := s.Migrations()
+			if err != nil {
+				return nil, err
+			}
+			available = make([]uint, 0, len(migrations))
+			for _, migration := range migrations {
+				available = append(available, migration.Version)
+			}
+		}
+
+		if d.Storage == s.Name() {
+			// stop right before the dependent version
+			c := at(lastVersion(available, func(v uint) bool { return v < d.Version }))
+			c.waits = append(c.waits, d)
+		} else {
+			// stop at the last version which doesn't exceed the one others depend on
+			c := at(lastVersion(available, func(v uint) bool { return v <= d.DependsOnVersion }))
+			c.publish = max(c.publish, d.DependsOnVersion)
+		}
+	}
+
+	checkpoints := make([]checkpoint, 0, len(byVersion))
+	for _, c := range byVersion {
+		checkpoints = append(checkpoints, *c)
+	}
+	sort.Slice(checkpoints, func(i, j int) bool { return checkpoints[i].version < checkpoints[j].version })
+	return checkpoints, nil
+}
+
+// lastVersion returns the greatest available version which matches, zero if there is no such one.
+func lastVersion(available []uint, match func(v uint) bool) uint {
+	var last uint
+	for _, v := range available {
+		if match(v) && v > last {
+			last = v
+		}
+	}
+	return last
+}
+
+// await moves the storage through its checkpoints publishing the reached versions and waiting for the dependencies,
+// the remaining migrations are left to the caller.
+func (m *Migrate) await(ctx context.Context, p *progress, s storage.Storager) error {
+	checkpoints, err := m.checkpoints(s)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range checkpoints {
+		current, err := upTo(ctx, s, c.version)
+		if err != nil {
+			return err
+		}
+		if c.publish > 0 {
+			p.reach(s.Name(), max(current, c.publish))
+		}
+
+		for _, d := range c.waits {
+			if d.Version > 0 && current >= d.Version {
+				// the dependent migrations were applied earlier
+				continue
+			}
+			if err = p.wait(ctx, d.DependsOn, d.DependsOnVersion); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// upTo migrates the storage up to the version unless it's already there (it never moves down),
+// the current version is returned.
+func upTo(ctx context.Context, s storage.Storager, version uint) (uint, error) {
+	current, _, err := s.Version(ctx)
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return 0, err
+	}
+	if version == 0 || current >= version {
+		return current, nil
+	}
+	if err = s.Migrate(ctx, version); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return 0, err
+	}
+	return version, nil
+}
+
+// awaitDependents waits until all storages which depend on the given one are migrated down.
+func (m *Migrate) awaitDependents(ctx context.Context, p *progress, s storage.Storager) error {
+	for _, d := range m.dependencies {
+		if d.DependsOn != s.Name() {
+			continue
+		}
+		if err := p.wait(ctx, d.Storage, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
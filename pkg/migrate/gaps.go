@@ -0,0 +1,157 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"github.com/Borislavv/go-logger/pkg/logger"
+	"github.com/Borislavv/go-migrate/pkg/migrate/source"
+	"github.com/Borislavv/go-migrate/pkg/migrate/storage"
+	"github.com/Borislavv/migrate/v4"
+	"sort"
+)
+
+// Gap describes the difference between applied and available migrations of a single storage.
+type Gap struct {
+	Storage string `json:"storage"`
+	Version uint   `json:"version"`
+	// OutOfOrder are available migrations below the current version which were never applied
+	// (e.g. merged from a branch after a higher version was applied).
+	OutOfOrder []source.Migration `json:"outOfOrder"`
+	// Missing are applied versions which files are absent in the migrations fs.
+	Missing []uint `json:"missing"`
+}
+
+// SetAllowOutOfOrder overrides MIGRATIONS_ALLOW_OUT_OF_ORDER, when allowed Up applies out-of-order migrations
+// of storages which support it (see storage.Applier) before the pending ones.
+func (m *Migrate) SetAllowOutOfOrder(allow bool) {
+	m.cfg.AllowOutOfOrder = allow
+}
+
+// Gaps returns out-of-order and missing migrations of each storage. The applied migrations are resolved from
+// the history, so storages without history are skipped and migrations below the first recorded one are considered
+// applied before the history was enabled.
+func (m *Migrate) Gaps(ctx context.Context) ([]Gap, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	gaps := make([]Gap, 0, len(m.storages))
+	for _, s := range m.storages {
+		gap, err := m.gap(ctx, s)
+		if err != nil {
+			return nil, m.logger.Fatal(ctx, errors.New("migrations: [storage: "+s.Name()+", action: Gaps]: error occurred while detecting gaps"), logger.Fields{
+				"err":     err.Error(),
+				"storage": s.Name(),
+			})
+		}
+		gaps = append(gaps, gap)
+	}
+
+	return gaps, nil
+}
+
+func (m *Migrate) gap(ctx context.Context, s storage.Storager) (Gap, error) {
+	gap := Gap{Storage: s.Name(), OutOfOrder: []source.Migration{}, Missing: []uint{}}
+
+	historian, ok := s.(storage.Historian)
+	if !ok {
+		return gap, nil
+	}
+
+	entries, err := historian.History(ctx)
+	if err != nil {
+		if errors.Is(err, storage.ErrHistoryIsNotEnabled) {
+			return gap, nil
+		}
+		return gap, err
+	}
+
+	var (
+		first   uint
+		applied = make(map[uint]bool, len(entries))
+	)
+	for _, entry := range entries {
+		if entry.Error != "" || entry.Direction == storage.HistoryRepair {
+			continue
+		}
+		if entry.Direction == storage.HistoryUp && first == 0 {
+			first = entry.Version
+		}
+		applied[entry.Version] = entry.Direction == storage.HistoryUp
+	}
+	if first == 0 {
+		return gap, nil
+	}
+
+	version, _, err := s.Version(ctx)
+	if err != nil {
+		if errors.Is(err, migrate.ErrNilVersion) {
+			return gap, nil
+		}
+		return gap, err
+	}
+	gap.Version = version
+
+	migrations, err := s.Migrations()
+	if err != nil {
+		return gap, err
+	}
+
+	available := make(map[uint]struct{}, len(migrations))
+	for _, migration := range migrations {
+		available[migration.Version] = struct{}{}
+		// the current version is applied even when it's dirty
+		if migration.Version >= first && migration.Version < version && !applied[migration.Version] && migration.Up != "" {
+			gap.OutOfOrder = append(gap.OutOfOrder, migration)
+		}
+	}
+	for v, ok := range applied {
+		if _, found := available[v]; ok && !found && v <= version {
+			gap.Missing = append(gap.Missing, v)
+		}
+	}
+	sort.Slice(gap.Missing, func(i, j int) bool { return gap.Missing[i] < gap.Missing[j] })
+
+	return gap, nil
+}
+
+// closeGaps reports the gaps of the storage and applies the out-of-order migrations when it's allowed.
+func (m *Migrate) closeGaps(ctx context.Context, s storage.Storager) error {
+	prefix := "migrations: [storage: " + s.Name() + ", action: Up]: "
+
+	gap, err := m.gap(ctx, s)
+	if err != nil {
+		return m.logger.Fatal(ctx, errors.New(prefix+"error occurred while detecting gaps"), logger.Fields{
+			"err":     err.Error(),
+			"storage": s.Name(),
+		})
+	}
+
+	for _, version := range gap.Missing {
+		m.logger.WarningMsg(ctx, prefix+"applied migration is missing in the migrations fs", logger.Fields{
+			"storage": s.Name(),
+			"version": version,
+		})
+	}
+
+	applier, ok := s.(storage.Applier)
+	for _, migration := range gap.OutOfOrder {
+		fields := logger.Fields{
+			"storage": s.Name(),
+			"version": migration.Version,
+			"file":    migration.Up,
+		}
+
+		if !m.cfg.AllowOutOfOrder || !ok {
+			m.logger.WarningMsg(ctx, prefix+"out-of-order migration is skipped, see MIGRATIONS_ALLOW_OUT_OF_ORDER", fields)
+			continue
+		}
+
+		if err = applier.Apply(ctx, migration); err != nil {
+			fields["err"] = err.Error()
+			return m.logger.Fatal(ctx, errors.New(prefix+"error occurred while applying out-of-order migration"), fields)
+		}
+		m.logger.InfoMsg(ctx, prefix+"out-of-order migration successfully applied", fields)
+	}
+
+	return nil
+}
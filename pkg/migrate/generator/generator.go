@@ -0,0 +1,147 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Borislavv/go-migrate/pkg/migrate/source"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrEmptyName              = errors.New("migration name is empty")
+	ErrUnknownVersionFormat   = errors.New("unknown version format")
+	ErrVersionAlreadyExists   = errors.New("migration version already exists in the target directory")
+	ErrFailedListMigrations   = errors.New("failed to list existing migrations")
+	ErrFailedCreateMigrations = errors.New("failed to create migration files")
+)
+
+// Format defines how the version of a new migration is chosen.
+type Format string
+
+const (
+	// Sequential takes the next number after the latest existing version (e.g. 0001, 0002).
+	Sequential Format = "sequential"
+	// Timestamp takes the current UTC time (e.g. 20240131235959), which avoids collisions between branches.
+	Timestamp Format = "timestamp"
+)
+
+const timestampLayout = "20060102150405"
+
+var nameReplacer = regexp.MustCompile(`[^a-z0-9]+`)
+
+type Config struct {
+	// Dir is the migrations directory (created when missing).
+	Dir string
+	// Ext is the files extension, e.g. sql (default) or json.
+	Ext string
+	// Format is the version format, Sequential by default.
+	Format Format
+	// Digits is the zero padding width of sequential versions, 4 by default.
+	Digits int
+}
+
+type Generator struct {
+	cfg Config
+	now func() time.Time
+}
+
+func New(cfg Config) *Generator {
+	if cfg.Format == "" {
+		cfg.Format = Sequential
+	}
+	if cfg.Digits <= 0 {
+		cfg.Digits = 4
+	}
+	if cfg.Ext = strings.TrimPrefix(cfg.Ext, "."); cfg.Ext == "" {
+		cfg.Ext = "sql"
+	}
+	return &Generator{cfg: cfg, now: time.Now}
+}
+
+// Create writes a pair of empty {version}_{name}.up.{ext} and {version}_{name}.down.{ext} files
+// with the version chosen by the configured format and returns their paths.
+func (g *Generator) Create(name string) (up, down string, err error) {
+	migrations, err := source.List(os.DirFS(g.cfg.Dir), ".")
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", "", fmt.Errorf("%w: %w", ErrFailedListMigrations, err)
+	}
+
+	var version string
+	switch g.cfg.Format {
+	case Sequential:
+		next := uint(1)
+		if len(migrations) > 0 {
+			next = migrations[len(migrations)-1].Version + 1
+		}
+		version = g.sequential(next)
+	case Timestamp:
+		version = g.now().UTC().Format(timestampLayout)
+	default:
+		return "", "", ErrUnknownVersionFormat
+	}
+
+	return g.create(version, name, migrations)
+}
+
+// CreateVersion writes a pair of empty migration files with the given version, zero padded like the sequential ones.
+func (g *Generator) CreateVersion(version uint, name string) (up, down string, err error) {
+	migrations, err := source.List(os.DirFS(g.cfg.Dir), ".")
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", "", fmt.Errorf("%w: %w", ErrFailedListMigrations, err)
+	}
+
+	return g.create(g.sequential(version), name, migrations)
+}
+
+// sequential pads the version by zeros up to the configured digits.
+func (g *Generator) sequential(version uint) string {
+	return fmt.Sprintf("%0*d", g.cfg.Digits, version)
+}
+
+func (g *Generator) create(version, name string, existing []source.Migration) (up, down string, err error) {
+	name = strings.Trim(nameReplacer.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" {
+		return "", "", ErrEmptyName
+	}
+
+	v, err := strconv.ParseUint(version, 10, 0)
+	if err != nil {
+		return "", "", err
+	}
+	for _, m := range existing {
+		if m.Version == uint(v) {
+			return "", "", fmt.Errorf("%w: %d (%s)", ErrVersionAlreadyExists, m.Version, m.Name)
+		}
+	}
+
+	if err = os.MkdirAll(g.cfg.Dir, 0755); err != nil {
+		return "", "", fmt.Errorf("%w: %w", ErrFailedCreateMigrations, err)
+	}
+
+	up = filepath.Join(g.cfg.Dir, fmt.Sprintf("%s_%s.up.%s", version, name, g.cfg.Ext))
+	down = filepath.Join(g.cfg.Dir, fmt.Sprintf("%s_%s.down.%s", version, name, g.cfg.Ext))
+
+	if err = touch(up); err != nil {
+		return "", "", fmt.Errorf("%w: %w", ErrFailedCreateMigrations, err)
+	}
+	if err = touch(down); err != nil {
+		_ = os.Remove(up)
+		return "", "", fmt.Errorf("%w: %w", ErrFailedCreateMigrations, err)
+	}
+
+	return up, down, nil
+}
+
+// touch creates an empty file, it fails when the file already exists.
+func touch(path string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
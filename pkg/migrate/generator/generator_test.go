@@ -0,0 +1,67 @@
+package generator
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestGenerator_CreateSequential(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "migrations")
+	g := New(Config{Dir: dir})
+
+	up, down, err := g.Create("Create users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if up != filepath.Join(dir, "0001_create_users.up.sql") || down != filepath.Join(dir, "0001_create_users.down.sql") {
+		t.Fatalf("unexpected files: %s, %s", up, down)
+	}
+
+	if up, _, err = g.Create("add_email"); err != nil {
+		t.Fatal(err)
+	}
+	if up != filepath.Join(dir, "0002_add_email.up.sql") {
+		t.Fatalf("unexpected file: %s", up)
+	}
+}
+
+func TestGenerator_CreateTimestamp(t *testing.T) {
+	dir := t.TempDir()
+	g := New(Config{Dir: dir, Ext: ".json", Format: Timestamp})
+	g.now = func() time.Time { return time.Date(2024, 1, 31, 23, 59, 59, 0, time.UTC) }
+
+	up, _, err := g.Create("seed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if up != filepath.Join(dir, "20240131235959_seed.up.json") {
+		t.Fatalf("unexpected file: %s", up)
+	}
+
+	if _, _, err = g.Create("seed_again"); !errors.Is(err, ErrVersionAlreadyExists) {
+		t.Fatalf("expected %v, got %v", ErrVersionAlreadyExists, err)
+	}
+}
+
+func TestGenerator_CreateVersion(t *testing.T) {
+	g := New(Config{Dir: t.TempDir()})
+
+	up, down, err := g.CreateVersion(7, "first")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(up) != "0007_first.up.sql" || filepath.Base(down) != "0007_first.down.sql" {
+		t.Fatalf("unexpected files: %s, %s", up, down)
+	}
+	if up, _, err = g.CreateVersion(20240501, "wide"); err != nil || filepath.Base(up) != "20240501_wide.up.sql" {
+		t.Fatalf("unexpected file: %s, %v", up, err)
+	}
+	if _, _, err := g.CreateVersion(7, "second"); !errors.Is(err, ErrVersionAlreadyExists) {
+		t.Fatalf("expected %v, got %v", ErrVersionAlreadyExists, err)
+	}
+	if _, _, err := g.CreateVersion(8, "!!!"); !errors.Is(err, ErrEmptyName) {
+		t.Fatalf("expected %v, got %v", ErrEmptyName, err)
+	}
+}
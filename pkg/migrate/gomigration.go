@@ -0,0 +1,15 @@
+package migrate
+
+import (
+	"github.com/Borislavv/go-migrate/pkg/migrate/storage"
+)
+
+// GoFunc is the Go code migration which receives the storage handle, see storage.GoFunc.
+type GoFunc = storage.GoFunc
+
+// RegisterGo adds the Go migration of the version to the storage (a driver or an instance name), it's interleaved
+// with the migration files in version order and tracked in the same version table, see storage.RegisterGo.
+// It's usually called from the init func of the package which contains the migration.
+func RegisterGo(storageName string, version uint, up, down GoFunc) {
+	storage.RegisterGo(storageName, version, up, down)
+}
@@ -0,0 +1,35 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Borislavv/go-logger/pkg/logger"
+	"github.com/Borislavv/go-migrate/pkg/migrate/storage"
+)
+
+var ErrHistoryIsNotSupported = errors.New("storage does not support migrations history")
+
+// History returns the executed migrations of the given storage ordered by the start time, see storage.Historian.
+func (m *Migrate) History(ctx context.Context, s storage.Storager) ([]storage.HistoryEntry, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	prefix := "migrations: [storage: " + s.Name() + ", action: History]: "
+
+	historian, ok := s.(storage.Historian)
+	if !ok {
+		return nil, m.logger.Fatal(ctx, fmt.Errorf("%s%w", prefix, ErrHistoryIsNotSupported), logger.Fields{
+			"storage": s.Name(),
+		})
+	}
+
+	entries, err := historian.History(ctx)
+	if err != nil {
+		return nil, m.logger.Fatal(ctx, errors.New(prefix+"error occurred while fetching history"), logger.Fields{
+			"err":     err.Error(),
+			"storage": s.Name(),
+		})
+	}
+	return entries, nil
+}
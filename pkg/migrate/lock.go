@@ -0,0 +1,72 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"github.com/Borislavv/go-logger/pkg/logger"
+	"github.com/Borislavv/go-migrate/pkg/migrate/storage"
+	"sort"
+	"time"
+)
+
+// lock acquires the distributed lock of each given storage which supports it (when enabled) in the name order,
+// so replicas which migrate the same storages can't deadlock each other. The returned func releases all of them.
+// Storages which don't support the lock are migrated without it, a warning is logged for each of them.
+func (m *Migrate) lock(ctx context.Context, action string, storages ...storage.Storager) (unlock func(), err error) {
+	if !m.cfg.LockEnabled {
+		return func() {}, nil
+	}
+
+	lockers := make([]storage.Storager, 0, len(storages))
+	for _, s := range storages {
+		if _, ok := s.(storage.Locker); !ok {
+			m.logger.WarningMsg(ctx, "migrations: [storage: "+s.Name()+", action: "+action+"]: storage does not support lock, migrating without it", logger.Fields{
+				"storage": s.Name(),
+			})
+			continue
+		}
+		lockers = append(lockers, s)
+	}
+	sort.Slice(lockers, func(i, j int) bool { return lockers[i].Name() < lockers[j].Name() })
+
+	waitCtx, cancel := ctx, context.CancelFunc(func() {})
+	if m.cfg.LockTimeout > 0 {
+		waitCtx, cancel = context.WithTimeout(ctx, m.cfg.LockTimeout)
+	}
+	defer cancel()
+
+	unlocks := make([]func() error, 0, len(lockers))
+	unlock = func() {
+		for i := len(unlocks) - 1; i >= 0; i-- {
+			if err := unlocks[i](); err != nil {
+				m.logger.WarningMsg(ctx, "migrations: [storage: "+lockers[i].Name()+", action: "+action+"]: failed to release lock", logger.Fields{
+					"err":     err.Error(),
+					"storage": lockers[i].Name(),
+				})
+			}
+		}
+	}
+
+	for _, s := range lockers {
+		prefix := "migrations: [storage: " + s.Name() + ", action: " + action + "]: "
+		started := time.Now()
+
+		release, err := s.(storage.Locker).Lock(waitCtx, m.cfg.LockKey+":"+s.Name())
+		if err != nil {
+			unlock()
+			return nil, m.logger.Fatal(ctx, errors.New(prefix+"failed to acquire lock"), logger.Fields{
+				"err":     err.Error(),
+				"storage": s.Name(),
+				"waited":  time.Since(started).String(),
+			})
+		}
+		unlocks = append(unlocks, release)
+
+		m.logger.InfoMsg(ctx, prefix+"lock acquired", logger.Fields{
+			"storage": s.Name(),
+			"waited":  time.Since(started).String(),
+		})
+	}
+
+	return unlock, nil
+}
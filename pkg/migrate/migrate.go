@@ -3,10 +3,12 @@ package migrate
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/Borislavv/go-logger/pkg/logger"
 	"github.com/Borislavv/go-migrate/pkg/migrate/storage"
 	"github.com/Borislavv/migrate/v4"
 	"golang.org/x/sync/errgroup"
+	"time"
 )
 
 var (
@@ -15,9 +17,11 @@ var (
 )
 
 type Migrate struct {
-	ctx      context.Context
-	logger   logger.Logger
-	storages []storage.Storager
+	cfg          *Config
+	logger       logger.Logger
+	storages     []storage.Storager
+	dependencies []Dependency
+	downPolicy   *DownPolicy
 }
 
 func New(ctx context.Context, logger logger.Logger, factory storage.Factorier) (*Migrate, error) {
@@ -30,23 +34,116 @@ func New(ctx context.Context, logger logger.Logger, factory storage.Factorier) (
 		return nil, logger.Fatal(ctx, ErrNoOneMigratorWasDefined, nil)
 	}
 
-	return &Migrate{
-		ctx:      ctx,
-		logger:   logger,
-		storages: storages,
-	}, nil
+	cfg, err := LoadConfig()
+	if err != nil {
+		return nil, logger.Fatal(ctx, fmt.Errorf("%w: %w", ErrFailedLoadConfig, err), nil)
+	}
+
+	downPolicy, err := LoadDownPolicy()
+	if err != nil {
+		return nil, logger.Fatal(ctx, fmt.Errorf("%w: %w", ErrFailedLoadDownPolicy, err), nil)
+	}
+
+	dependencies := make([]Dependency, 0, len(cfg.Dependencies))
+	for _, raw := range cfg.Dependencies {
+		dependency, err := ParseDependency(raw)
+		if err != nil {
+			return nil, logger.Fatal(ctx, fmt.Errorf("%w: %w", ErrFailedLoadConfig, err), nil)
+		}
+		dependencies = append(dependencies, dependency)
+	}
+
+	m := &Migrate{
+		cfg:        cfg,
+		logger:     logger,
+		storages:   storages,
+		downPolicy: downPolicy,
+	}
+	if err = m.SetChecksumPolicy(cfg.ChecksumPolicy); err != nil {
+		return nil, logger.Fatal(ctx, fmt.Errorf("%w: %w", ErrFailedLoadConfig, err), nil)
+	}
+	if err = m.SetRepairPolicy(cfg.RepairPolicy); err != nil {
+		return nil, logger.Fatal(ctx, fmt.Errorf("%w: %w", ErrFailedLoadConfig, err), nil)
+	}
+	if err = m.SetDependencies(dependencies...); err != nil {
+		return nil, logger.Fatal(ctx, fmt.Errorf("%w: %w", ErrFailedLoadConfig, err), nil)
+	}
+
+	return m, nil
+}
+
+// SetTimeout overrides the global timeout loaded from env (zero means unlimited).
+func (m *Migrate) SetTimeout(timeout time.Duration) {
+	m.cfg.Timeout = timeout
+}
+
+// SetLock enables the distributed lock with the given key and wait timeout (overrides MIGRATIONS_LOCK_*).
+func (m *Migrate) SetLock(key string, timeout time.Duration) {
+	m.cfg.LockEnabled = true
+	m.cfg.LockKey = key
+	m.cfg.LockTimeout = timeout
+}
+
+// SetAppVersion overrides the application version recorded into the history (MIGRATIONS_APP_VERSION).
+func (m *Migrate) SetAppVersion(version string) {
+	m.cfg.AppVersion = version
+}
+
+// SetDownPolicy overrides the down policy loaded from env, nil sets the default one (the zero value).
+func (m *Migrate) SetDownPolicy(policy *DownPolicy) {
+	if policy == nil {
+		policy = new(DownPolicy)
+	}
+	m.downPolicy = policy
+}
+
+// DownPolicy returns the current down policy.
+func (m *Migrate) DownPolicy() *DownPolicy {
+	return m.downPolicy
 }
 
-// Up executes each migrator in parallel wrapping them in errgroup without context.
-func (m *Migrate) Up() error {
+// Up executes each migrator in parallel wrapping them in errgroup,
+// the ctx (limited by the global timeout) is propagated into each storage.
+// A storage with dependencies waits for them (see Dependency), a failed dependency fails its dependents.
+// Applied migrations are verified against the recorded checksums first (see Verify),
+// out-of-order ones are reported or applied before the pending ones (see Gaps).
+func (m *Migrate) Up(ctx context.Context) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	unlock, err := m.lock(ctx, "Up", m.storages...)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if m.cfg.ChecksumPolicy != ChecksumIgnore {
+		if _, err = m.verify(ctx, "Up", m.storages...); err != nil {
+			return err
+		}
+	}
+
 	eg := &errgroup.Group{}
-	ctx := context.Background()
+	p := newProgress()
 
 	for _, migrator := range m.storages {
-		eg.Go(func() error {
+		eg.Go(func() (err error) {
+			defer func() { p.finish(migrator.Name(), err) }()
+
 			prefix := "migrations: [storage: " + migrator.Name() + ", action: Up]: "
 
-			if err := migrator.Up(); err != nil {
+			if err := m.await(ctx, p, migrator); err != nil {
+				return m.logger.Fatal(ctx, errors.New(prefix+"error occurred while waiting for dependencies"), logger.Fields{
+					"err":     err.Error(),
+					"storage": migrator.Name(),
+				})
+			}
+
+			if err := m.closeGaps(ctx, migrator); err != nil {
+				return err
+			}
+
+			if err := migrator.Up(ctx); err != nil {
 				if errors.Is(err, migrate.ErrNoChange) {
 					m.logger.InfoMsg(ctx, prefix+"no changes detected", logger.Fields{
 						"storage": migrator.Name(),
@@ -70,16 +167,43 @@ func (m *Migrate) Up() error {
 	return eg.Wait()
 }
 
-// Down executes each migrator in parallel wrapping them in errgroup without context.
-func (m *Migrate) Down() error {
+// Down executes each migrator in parallel wrapping them in errgroup,
+// the ctx (limited by the global timeout) is propagated into each storage.
+// The down policy is checked under the lock before any migration is executed
+// and a storage waits for its dependents to be downgraded.
+func (m *Migrate) Down(ctx context.Context) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	unlock, err := m.lock(ctx, "Down", m.storages...)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err = m.downPolicy.Check(-1); err != nil {
+		return m.logger.Fatal(ctx, errors.New("migrations: [action: Down]: rejected by down policy"), logger.Fields{
+			"err": err.Error(),
+		})
+	}
+
 	eg := &errgroup.Group{}
-	ctx := context.Background()
+	p := newProgress()
 
 	for _, migrator := range m.storages {
-		eg.Go(func() error {
+		eg.Go(func() (err error) {
+			defer func() { p.finish(migrator.Name(), err) }()
+
 			prefix := "migrations: [storage: " + migrator.Name() + ", action: Down]: "
 
-			if err := migrator.Down(); err != nil {
+			if err := m.awaitDependents(ctx, p, migrator); err != nil {
+				return m.logger.Fatal(ctx, errors.New(prefix+"error occurred while waiting for dependents"), logger.Fields{
+					"err":     err.Error(),
+					"storage": migrator.Name(),
+				})
+			}
+
+			if err := migrator.Down(ctx); err != nil {
 				if errors.Is(err, migrate.ErrNoChange) {
 					m.logger.InfoMsg(ctx, prefix+"no changes detected", logger.Fields{
 						"storage": migrator.Name(),
@@ -103,10 +227,149 @@ func (m *Migrate) Down() error {
 	return eg.Wait()
 }
 
-func (m *Migrate) Force(n int, storage storage.Storager) error {
-	if err := storage.Force(n); err != nil {
+// Migrate moves the given storage up or down to the target version.
+// Moving down is checked against the down policy under the lock, the steps are the migrations between the target
+// and the current version. An error of fetching the current version rejects the call.
+func (m *Migrate) Migrate(ctx context.Context, version uint, storage storage.Storager) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	prefix := "migrations: [storage: " + storage.Name() + ", action: Migrate]: "
+
+	unlock, err := m.lock(ctx, "Migrate", storage)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	steps, err := m.downSteps(ctx, storage, version)
+	if err != nil {
+		return m.logger.Fatal(ctx, errors.New(prefix+"error occurred while fetching state"), logger.Fields{
+			"err":     err.Error(),
+			"storage": storage.Name(),
+			"version": version,
+		})
+	}
+	if steps > 0 {
+		if err = m.downPolicy.Check(steps); err != nil {
+			return m.logger.Fatal(ctx, errors.New(prefix+"rejected by down policy"), logger.Fields{
+				"err":     err.Error(),
+				"storage": storage.Name(),
+				"version": version,
+				"steps":   steps,
+			})
+		}
+	}
+
+	if err := storage.Migrate(ctx, version); err != nil {
+		if errors.Is(err, migrate.ErrNoChange) {
+			m.logger.InfoMsg(ctx, prefix+"no changes detected", logger.Fields{
+				"storage": storage.Name(),
+				"version": version,
+			})
+			return nil
+		}
+
+		return m.logger.Fatal(ctx, errors.New(prefix+"error occurred while migrating to version"), logger.Fields{
+			"err":     err.Error(),
+			"storage": storage.Name(),
+			"version": version,
+		})
+	}
+
+	m.logger.InfoMsg(ctx, prefix+"schema successfully migrated to version", logger.Fields{
+		"storage": storage.Name(),
+		"version": version,
+	})
+	return nil
+}
+
+// downSteps returns the number of migrations which would be rolled back by moving the storage to the version.
+func (m *Migrate) downSteps(ctx context.Context, storage storage.Storager, version uint) (int, error) {
+	current, _, err := storage.Version(ctx)
+	if err != nil {
+		if errors.Is(err, migrate.ErrNilVersion) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	if version >= current {
+		return 0, nil
+	}
+
+	migrations, err := storage.Migrations()
+	if err != nil {
+		return 0, err
+	}
+
+	steps := 0
+	for _, migration := range migrations {
+		if migration.Version > version && migration.Version <= current {
+			steps++
+		}
+	}
+	return steps, nil
+}
+
+// Steps applies n migrations on the given storage when n > 0 or rolls back the last |n| when n < 0.
+func (m *Migrate) Steps(ctx context.Context, n int, storage storage.Storager) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	prefix := "migrations: [storage: " + storage.Name() + ", action: Steps]: "
+
+	unlock, err := m.lock(ctx, "Steps", storage)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if n < 0 {
+		if err := m.downPolicy.Check(-n); err != nil {
+			return m.logger.Fatal(ctx, errors.New(prefix+"rejected by down policy"), logger.Fields{
+				"err":     err.Error(),
+				"storage": storage.Name(),
+				"steps":   n,
+			})
+		}
+	}
+
+	if err := storage.Steps(ctx, n); err != nil {
+		if errors.Is(err, migrate.ErrNoChange) {
+			m.logger.InfoMsg(ctx, prefix+"no changes detected", logger.Fields{
+				"storage": storage.Name(),
+				"steps":   n,
+			})
+			return nil
+		}
+
+		return m.logger.Fatal(ctx, errors.New(prefix+"error occurred while applying steps"), logger.Fields{
+			"err":     err.Error(),
+			"storage": storage.Name(),
+			"steps":   n,
+		})
+	}
+
+	m.logger.InfoMsg(ctx, prefix+"steps successfully applied", logger.Fields{
+		"storage": storage.Name(),
+		"steps":   n,
+	})
+	return nil
+}
+
+func (m *Migrate) Force(ctx context.Context, n int, storage storage.Storager) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	unlock, err := m.lock(ctx, "Force", storage)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := storage.Force(ctx, n); err != nil {
 		return m.logger.Fatal(
-			context.Background(),
+			ctx,
 			errors.New("migrations: [storage: "+storage.Name()+", action: Force]: error occurred while force migrate to version"),
 			logger.Fields{
 				"err":     err.Error(),
@@ -117,10 +380,13 @@ func (m *Migrate) Force(n int, storage storage.Storager) error {
 	return nil
 }
 
-func (m *Migrate) Version(storage storage.Storager) (version uint, dirty bool, err error) {
-	if version, dirty, err = storage.Version(); err != nil {
+func (m *Migrate) Version(ctx context.Context, storage storage.Storager) (version uint, dirty bool, err error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	if version, dirty, err = storage.Version(ctx); err != nil {
 		return version, dirty, m.logger.Fatal(
-			context.Background(),
+			ctx,
 			errors.New("migrations: [storage: "+storage.Name()+", action: Version]: error occurred while fetching state"),
 			logger.Fields{
 				"err":     err.Error(),
@@ -135,3 +401,12 @@ func (m *Migrate) Version(storage storage.Storager) (version uint, dirty bool, e
 func (m *Migrate) Storages() []storage.Storager {
 	return m.storages
 }
+
+// withTimeout limits ctx by the global timeout (if any), the ctx also carries the app version for the history.
+func (m *Migrate) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx = storage.WithAppVersion(ctx, m.cfg.AppVersion)
+	if m.cfg.Timeout > 0 {
+		return context.WithTimeout(ctx, m.cfg.Timeout)
+	}
+	return context.WithCancel(ctx)
+}
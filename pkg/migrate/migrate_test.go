@@ -2,29 +2,213 @@ package migrate
 
 import (
 	"context"
+	"errors"
 	"github.com/Borislavv/go-logger/pkg/logger"
 	loggerenum "github.com/Borislavv/go-logger/pkg/logger/enum"
+	"github.com/Borislavv/go-migrate/pkg/migrate/source"
 	"github.com/Borislavv/go-migrate/pkg/migrate/storage"
+	"github.com/Borislavv/migrate/v4"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 type TestFactory struct {
+	storages []storage.Storager
 }
 
 func (f *TestFactory) Make(_ context.Context) ([]storage.Storager, error) {
+	if f.storages != nil {
+		return f.storages, nil
+	}
 	return []storage.Storager{&TestStorage{}}, nil
 }
 
 type TestStorage struct {
+	name       string
+	version    uint
+	dirty      bool
+	migrations []source.Migration
 }
 
 func (s *TestStorage) Name() string {
+	if s.name != "" {
+		return s.name
+	}
 	return "test"
 }
-func (s *TestStorage) Up() error {
+func (s *TestStorage) Up(_ context.Context) error {
+	return nil
+}
+func (s *TestStorage) Down(_ context.Context) error {
+	return nil
+}
+func (s *TestStorage) Migrate(_ context.Context, _ uint) error {
+	return nil
+}
+func (s *TestStorage) Steps(_ context.Context, _ int) error {
+	return nil
+}
+func (s *TestStorage) Force(_ context.Context, _ int) error {
+	return nil
+}
+func (s *TestStorage) Version(_ context.Context) (version uint, dirty bool, err error) {
+	if s.version == 0 {
+		return 0, false, migrate.ErrNilVersion
+	}
+	return s.version, s.dirty, nil
+}
+func (s *TestStorage) Migrations() ([]source.Migration, error) {
+	return s.migrations, nil
+}
+func (s *TestStorage) ReadMigration(path string) ([]byte, error) {
+	return []byte("-- " + path), nil
+}
+
+// BlockingStorage blocks on Up until the ctx is done.
+type BlockingStorage struct {
+	TestStorage
+}
+
+func (s *BlockingStorage) Up(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// LockingStorage implements storage.Locker on top of the in-memory set of held keys.
+type LockingStorage struct {
+	TestStorage
+	mu   sync.Mutex
+	held map[string]bool
+}
+
+func (s *LockingStorage) Name() string {
+	return "locking"
+}
+
+func (s *LockingStorage) Lock(ctx context.Context, key string) (func() error, error) {
+	if err := storage.PollLock(ctx, func(_ context.Context) (bool, error) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.held[key] {
+			return false, nil
+		}
+		s.held[key] = true
+		return true, nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return func() error {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.held, key)
+		return nil
+	}, nil
+}
+
+type StepsStorage struct {
+	TestStorage
+	steps []int
+	err   error
+}
+
+func (s *StepsStorage) Steps(_ context.Context, n int) error {
+	s.steps = append(s.steps, n)
+	return s.err
+}
+
+type BrokenStorage struct {
+	TestStorage
+}
+
+func (s *BrokenStorage) Version(_ context.Context) (version uint, dirty bool, err error) {
+	return 0, false, errors.New("connection refused")
+}
+
+// HistoryStorage implements storage.Historian returning the given entries.
+type HistoryStorage struct {
+	TestStorage
+	entries []storage.HistoryEntry
+}
+
+func (s *HistoryStorage) History(_ context.Context) ([]storage.HistoryEntry, error) {
+	return s.entries, nil
+}
+
+// ApplyingStorage implements storage.Applier on top of the HistoryStorage recording the applied versions.
+type ApplyingStorage struct {
+	HistoryStorage
+	applied []uint
+}
+
+func (s *ApplyingStorage) Apply(_ context.Context, migration source.Migration) error {
+	s.applied = append(s.applied, migration.Version)
+	s.entries = append(s.entries, storage.HistoryEntry{Version: migration.Version, Direction: storage.HistoryUp})
+	return nil
+}
+
+// DirtyStorage implements storage.Repairer on top of the HistoryStorage, it's dirty until repaired.
+type DirtyStorage struct {
+	HistoryStorage
+	target int
+	down   bool
+	fixed  bool
+}
+
+func (s *DirtyStorage) Version(_ context.Context) (version uint, dirty bool, err error) {
+	return s.version, !s.fixed, nil
+}
+
+func (s *DirtyStorage) Repair(_ context.Context, _ source.Migration, version int, down bool) error {
+	s.target, s.down, s.fixed = version, down, true
+	return nil
+}
+
+// JournalStorage applies its migrations one by one writing them into the shared journal.
+type JournalStorage struct {
+	TestStorage
+	name    string
+	journal *Journal
+}
+
+type Journal struct {
+	mu      sync.Mutex
+	entries []string
+}
+
+func (j *Journal) write(entry string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.entries = append(j.entries, entry)
+}
+
+func (s *JournalStorage) Name() string {
+	return s.name
+}
+func (s *JournalStorage) Up(ctx context.Context) error {
+	return s.Migrate(ctx, s.migrations[len(s.migrations)-1].Version)
+}
+func (s *JournalStorage) Down(_ context.Context) error {
+	s.journal.write(s.name + " down")
+	s.version = 0
 	return nil
 }
-func (s *TestStorage) Down() error {
+func (s *JournalStorage) Migrate(_ context.Context, version uint) error {
+	if version <= s.version {
+		return migrate.ErrNoChange
+	}
+	for _, migration := range s.migrations {
+		if migration.Version > s.version && migration.Version <= version {
+			// gives a chance to the concurrent storages to run ahead if nothing stops them
+			time.Sleep(time.Millisecond)
+			s.journal.write(s.name + "@" + strconv.Itoa(int(migration.Version)))
+		}
+	}
+	s.version = version
 	return nil
 }
 
@@ -46,7 +230,703 @@ func TestMigrate_Up(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if err = m.Up(); err != nil {
+	if err = m.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMigrate_Steps(t *testing.T) {
+	out, cancel, err := logger.NewOutput(loggerenum.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel()
+
+	lgr, cancel, err := logger.NewLogrus(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel()
+
+	first, second := &StepsStorage{TestStorage: TestStorage{name: "first"}}, &StepsStorage{TestStorage: TestStorage{name: "second"}}
+	m, err := New(context.Background(), lgr, &TestFactory{storages: []storage.Storager{first, second}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// n reaches the given storage as is
+	if err = m.Steps(context.Background(), 2, first); err != nil {
+		t.Fatal(err)
+	}
+	if err = m.Steps(context.Background(), -1, second); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(first.steps, []int{2}) || !slices.Equal(second.steps, []int{-1}) {
+		t.Fatalf("unexpected steps: %v, %v", first.steps, second.steps)
+	}
+
+	// only the negative n is checked against the down policy
+	m.SetDownPolicy(&DownPolicy{MaxSteps: 1})
+	if err = m.Steps(context.Background(), -2, first); err == nil {
+		t.Fatal("expected the rollback over the max steps to be rejected")
+	}
+	if err = m.Steps(context.Background(), 3, first); err != nil {
+		t.Fatal(err)
+	}
+	m.SetDownPolicy(&DownPolicy{Env: "production"})
+	if err = m.Steps(context.Background(), -1, first); err == nil {
+		t.Fatal("expected the rollback in production to be rejected")
+	}
+	if err = m.Steps(context.Background(), 1, first); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(first.steps, []int{2, 3, 1}) {
+		t.Fatalf("the rejected steps must not reach the storage: %v", first.steps)
+	}
+
+	// nothing to apply is not an error
+	second.err = migrate.ErrNoChange
+	if err = m.Steps(context.Background(), 1, second); err != nil {
+		t.Fatalf("expected ErrNoChange to be tolerated, got: %v", err)
+	}
+	second.err = errors.New("syntax error")
+	if err = m.Steps(context.Background(), 1, second); err == nil {
+		t.Fatal("expected the storage error")
+	}
+}
+
+func TestDownPolicy_Check(t *testing.T) {
+	cases := []struct {
+		name   string
+		policy DownPolicy
+		steps  int
+		err    error
+	}{
+		{name: "default", policy: DownPolicy{}, steps: -1},
+		{name: "production", policy: DownPolicy{Env: "production"}, steps: 1, err: ErrDownIsDisabledInProduction},
+		{name: "production allowed", policy: DownPolicy{Env: "prod", AllowInProduction: true}, steps: 1},
+		{name: "token mismatch", policy: DownPolicy{ConfirmationToken: "yes", Confirmation: "no"}, steps: 1, err: ErrDownConfirmationMismatch},
+		{name: "token match", policy: DownPolicy{ConfirmationToken: "yes", Confirmation: "yes"}, steps: 1},
+		{name: "full rollback with limit", policy: DownPolicy{MaxSteps: 2}, steps: -1, err: ErrDownFullRollbackIsNotAllowed},
+		{name: "limit exceeded", policy: DownPolicy{MaxSteps: 2}, steps: 3, err: ErrDownStepsLimitExceeded},
+		{name: "within limit", policy: DownPolicy{MaxSteps: 2}, steps: 2},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if err := c.policy.Check(c.steps); !errors.Is(err, c.err) {
+				t.Fatalf("expected %v, got %v", c.err, err)
+			}
+		})
+	}
+}
+
+func TestMigrate_MigrateDownPolicy(t *testing.T) {
+	out, cancel, err := logger.NewOutput(loggerenum.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel()
+
+	lgr, cancel, err := logger.NewLogrus(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel()
+
+	migrations := []source.Migration{{Version: 1}, {Version: 2}, {Version: 3}, {Version: 4}}
+	cases := []struct {
+		name    string
+		policy  *DownPolicy
+		storage storage.Storager
+		version uint
+		fail    bool
+	}{
+		{name: "nil policy", storage: &TestStorage{version: 4, migrations: migrations}, version: 1},
+		{name: "within limit", policy: &DownPolicy{MaxSteps: 3}, storage: &TestStorage{version: 4, migrations: migrations}, version: 1},
+		{name: "limit exceeded", policy: &DownPolicy{MaxSteps: 2}, storage: &TestStorage{version: 4, migrations: migrations}, version: 1, fail: true},
+		{name: "production", policy: &DownPolicy{Env: "production"}, storage: &TestStorage{version: 4, migrations: migrations}, version: 3, fail: true},
+		{name: "up in production", policy: &DownPolicy{Env: "production"}, storage: &TestStorage{version: 2, migrations: migrations}, version: 4},
+		{name: "nothing applied", policy: &DownPolicy{Env: "production"}, storage: &TestStorage{migrations: migrations}, version: 4},
+		{name: "version error", policy: &DownPolicy{}, storage: &BrokenStorage{}, version: 1, fail: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			m, err := New(context.Background(), lgr, &TestFactory{storages: []storage.Storager{c.storage}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			m.SetDownPolicy(c.policy)
+
+			if err = m.Migrate(context.Background(), c.version, c.storage); (err != nil) != c.fail {
+				t.Fatalf("expected failure: %v, got %v", c.fail, err)
+			}
+		})
+	}
+}
+
+func TestMigrate_UpTimeout(t *testing.T) {
+	out, cancel, err := logger.NewOutput(loggerenum.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel()
+
+	lgr, cancel, err := logger.NewLogrus(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel()
+
+	m, err := New(context.Background(), lgr, &TestFactory{storages: []storage.Storager{&BlockingStorage{}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.SetTimeout(10 * time.Millisecond)
+
+	if err = m.Up(context.Background()); err == nil {
+		t.Fatal("expected an error due to timeout")
+	}
+}
+
+func TestMigrate_Status(t *testing.T) {
+	out, cancel, err := logger.NewOutput(loggerenum.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel()
+
+	lgr, cancel, err := logger.NewLogrus(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel()
+
+	migrations := []source.Migration{{Version: 1, Name: "first"}, {Version: 2, Name: "second"}, {Version: 3, Name: "third"}}
+	m, err := New(context.Background(), lgr, &TestFactory{storages: []storage.Storager{
+		&TestStorage{version: 2, migrations: migrations},
+		&TestStorage{migrations: migrations},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := m.Status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Storages) != 2 {
+		t.Fatalf("expected 2 storages, got %d", len(report.Storages))
+	}
+	if s := report.Storages[0]; s.Version != 2 || len(s.Applied) != 2 || len(s.Pending) != 1 {
+		t.Fatalf("unexpected status: %+v", s)
+	}
+	if s := report.Storages[1]; s.Version != 0 || len(s.Applied) != 0 || len(s.Pending) != 3 {
+		t.Fatalf("unexpected status: %+v", s)
+	}
+}
+
+func TestMigrate_Plan(t *testing.T) {
+	out, cancel, err := logger.NewOutput(loggerenum.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel()
+
+	lgr, cancel, err := logger.NewLogrus(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel()
+
+	migrations := []source.Migration{
+		{Version: 1, Name: "first", Up: "1_first.up.sql", Down: "1_first.down.sql"},
+		{Version: 2, Name: "second", Up: "2_second.up.sql", Down: "2_second.down.sql"},
+		{Version: 3, Name: "third", Up: "3_third.up.sql"},
+	}
+	m, err := New(context.Background(), lgr, &TestFactory{storages: []storage.Storager{
+		&TestStorage{version: 2, migrations: migrations},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	up, err := m.Plan(context.Background(), Up)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if steps := up.Storages[0].Steps; len(steps) != 1 || steps[0].File != "3_third.up.sql" ||
+		steps[0].Checksum != source.Checksum([]byte("-- 3_third.up.sql")) {
+		t.Fatalf("unexpected up plan: %+v", steps)
+	}
+
+	down, err := m.Plan(context.Background(), Down)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if steps := down.Storages[0].Steps; len(steps) != 2 || steps[0].Version != 2 || steps[1].Version != 1 {
+		t.Fatalf("unexpected down plan: %+v", steps)
+	}
+
+	// Up and Down fail with ErrDirty, so nothing is planned until the storage is repaired
+	m, err = New(context.Background(), lgr, &TestFactory{storages: []storage.Storager{
+		&TestStorage{version: 2, dirty: true, migrations: migrations},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, direction := range []Direction{Up, Down} {
+		plan, err := m.Plan(context.Background(), direction)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sp := plan.Storages[0]; !sp.Dirty || sp.Version != 2 || len(sp.Steps) != 0 {
+			t.Fatalf("unexpected %s plan of the dirty storage: %+v", direction, sp)
+		}
+	}
+}
+
+func TestMigrate_Lock(t *testing.T) {
+	out, cancel, err := logger.NewOutput(loggerenum.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel()
+
+	lgr, cancel, err := logger.NewLogrus(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel()
+
+	// the lock is held by another replica
+	s := &LockingStorage{held: map[string]bool{"test:locking": true}}
+
+	m, err := New(context.Background(), lgr, &TestFactory{storages: []storage.Storager{s, &TestStorage{}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.SetLock("test", 10*time.Millisecond)
+
+	if err = m.Up(context.Background()); err == nil {
+		t.Fatal("expected an error due to the lock wait timeout")
+	}
+
+	s.mu.Lock()
+	delete(s.held, "test:locking")
+	s.mu.Unlock()
+
+	if err = m.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.held) != 0 {
+		t.Fatalf("expected the lock to be released, held: %v", s.held)
+	}
+}
+
+func TestParseDependency(t *testing.T) {
+	cases := []struct {
+		raw      string
+		expected Dependency
+		err      error
+	}{
+		{raw: "mongodb:postgres", expected: Dependency{Storage: "mongodb", DependsOn: "postgres"}},
+		{raw: "mongodb@5:postgres@12", expected: Dependency{Storage: "mongodb", Version: 5, DependsOn: "postgres", DependsOnVersion: 12}},
+		{raw: "mongodb", err: ErrInvalidDependency},
+		{raw: "mongodb@five:postgres", err: ErrInvalidDependency},
+		{raw: ":postgres", err: ErrInvalidDependency},
+	}
+
+	for _, c := range cases {
+		t.Run(c.raw, func(t *testing.T) {
+			d, err := ParseDependency(c.raw)
+			if !errors.Is(err, c.err) {
+				t.Fatalf("expected %v, got %v", c.err, err)
+			}
+			if d != c.expected {
+				t.Fatalf("expected %+v, got %+v", c.expected, d)
+			}
+			if err == nil && d.String() != c.raw {
+				t.Fatalf("expected %q, got %q", c.raw, d.String())
+			}
+		})
+	}
+}
+
+func TestMigrate_Dependencies(t *testing.T) {
+	out, cancel, err := logger.NewOutput(loggerenum.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel()
+
+	lgr, cancel, err := logger.NewLogrus(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel()
+
+	migrations := []source.Migration{{Version: 1}, {Version: 2}, {Version: 3}}
+	journal := &Journal{}
+	mongo := &JournalStorage{name: "mongodb", journal: journal, TestStorage: TestStorage{migrations: migrations}}
+	postgres := &JournalStorage{name: "postgres", journal: journal, TestStorage: TestStorage{migrations: migrations}}
+	redis := &JournalStorage{name: "redis", journal: journal, TestStorage: TestStorage{migrations: migrations}}
+
+	m, err := New(context.Background(), lgr, &TestFactory{storages: []storage.Storager{redis, mongo, postgres}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = m.SetDependencies(Dependency{Storage: "redis", DependsOn: "mongodb"}, Dependency{Storage: "mongodb", DependsOn: "redis"}); !errors.Is(err, ErrDependencyCycle) {
+		t.Fatalf("expected %v, got %v", ErrDependencyCycle, err)
+	}
+	if err = m.SetDependencies(
+		Dependency{Storage: "mongodb", Version: 2, DependsOn: "postgres", DependsOnVersion: 2},
+		Dependency{Storage: "redis", DependsOn: "mongodb"},
+		Dependency{Storage: "redis", DependsOn: "disabled"},
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	names := make([]string, 0, len(m.Storages()))
+	for _, s := range m.Storages() {
+		names = append(names, s.Name())
+	}
+	if strings.Join(names, ",") != "postgres,mongodb,redis" {
+		t.Fatalf("unexpected storages order: %v", names)
+	}
+
+	if err = m.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	before := func(entries []string, first, second string) bool {
+		return slices.Index(entries, first) < slices.Index(entries, second)
+	}
+	entries := journal.entries
+	if len(entries) != 9 ||
+		!before(entries, "postgres@2", "mongodb@2") ||
+		!before(entries, "mongodb@3", "redis@1") {
+		t.Fatalf("unexpected up order: %v", entries)
+	}
+
+	journal.entries = nil
+	if err = m.Down(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(journal.entries, ",") != "redis down,mongodb down,postgres down" {
+		t.Fatalf("unexpected down order: %v", journal.entries)
+	}
+}
+
+func TestMigrate_Verify(t *testing.T) {
+	out, cancel, err := logger.NewOutput(loggerenum.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel()
+
+	lgr, cancel, err := logger.NewLogrus(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel()
+
+	s := &HistoryStorage{
+		TestStorage: TestStorage{version: 2, migrations: []source.Migration{
+			{Version: 1, Name: "first", Up: "1_first.up.sql"},
+			{Version: 2, Name: "second", Up: "2_second.up.sql"},
+			{Version: 3, Name: "third", Up: "3_third.up.sql"},
+		}},
+		entries: []storage.HistoryEntry{
+			{Version: 1, Direction: storage.HistoryUp, Checksum: source.Checksum([]byte("-- 1_first.up.sql"))},
+			{Version: 2, Direction: storage.HistoryUp, Checksum: "changed"},
+			{Version: 2, Direction: storage.HistoryDown, Checksum: "changed"},
+			// only the last successful up is compared
+			{Version: 2, Direction: storage.HistoryUp, Checksum: "changed again"},
+			{Version: 2, Direction: storage.HistoryUp, Checksum: "failed", Error: "syntax error"},
+			// not applied yet
+			{Version: 3, Direction: storage.HistoryUp, Checksum: "rolled back"},
+		},
+	}
+
+	m, err := New(context.Background(), lgr, &TestFactory{storages: []storage.Storager{s}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mismatches, err := m.Verify(context.Background())
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("expected %v, got %v", ErrChecksumMismatch, err)
+	}
+	if len(mismatches) != 1 || mismatches[0].Version != 2 || mismatches[0].Applied != "changed again" ||
+		mismatches[0].Actual != source.Checksum([]byte("-- 2_second.up.sql")) {
+		t.Fatalf("unexpected mismatches: %+v", mismatches)
+	}
+	if err = m.Up(context.Background()); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("expected %v, got %v", ErrChecksumMismatch, err)
+	}
+
+	if err = m.SetChecksumPolicy(ChecksumWarn); err != nil {
+		t.Fatal(err)
+	}
+	if mismatches, err = m.Verify(context.Background()); err != nil || len(mismatches) != 1 {
+		t.Fatalf("unexpected result: %+v, %v", mismatches, err)
+	}
+	if err = m.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = m.SetChecksumPolicy("strict"); !errors.Is(err, ErrUnknownChecksumPolicy) {
+		t.Fatalf("expected %v, got %v", ErrUnknownChecksumPolicy, err)
+	}
+
+	// go migrations are not verified
+	RegisterGo("verify-go", 1, func(context.Context, any) error { return nil }, nil)
+	goStorage := &HistoryStorage{
+		TestStorage: TestStorage{name: "verify-go", version: 1, migrations: []source.Migration{
+			{Version: 1, Name: storage.GoMigrationName, Up: "go:1.up"},
+		}},
+		entries: []storage.HistoryEntry{{Version: 1, Direction: storage.HistoryUp, Checksum: "recorded"}},
+	}
+	if m, err = New(context.Background(), lgr, &TestFactory{storages: []storage.Storager{goStorage}}); err != nil {
+		t.Fatal(err)
+	}
+	if mismatches, err = m.Verify(context.Background()); err != nil || len(mismatches) != 0 {
+		t.Fatalf("unexpected result: %+v, %v", mismatches, err)
+	}
+}
+
+func TestMigrate_Gaps(t *testing.T) {
+	out, cancel, err := logger.NewOutput(loggerenum.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel()
+
+	lgr, cancel, err := logger.NewLogrus(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel()
+
+	s := &ApplyingStorage{HistoryStorage: HistoryStorage{
+		TestStorage: TestStorage{version: 5, migrations: []source.Migration{
+			// applied before the history was enabled
+			{Version: 1, Name: "first", Up: "1_first.up.sql"},
+			{Version: 3, Name: "third", Up: "3_third.up.sql"},
+			// merged after 5 was applied
+			{Version: 4, Name: "fourth", Up: "4_fourth.up.sql"},
+			{Version: 5, Name: "fifth", Up: "5_fifth.up.sql"},
+			{Version: 6, Name: "sixth", Up: "6_sixth.up.sql"},
+		}},
+		entries: []storage.HistoryEntry{
+			{Version: 2, Direction: storage.HistoryUp},
+			{Version: 3, Direction: storage.HistoryUp},
+			{Version: 4, Direction: storage.HistoryUp, Error: "syntax error"},
+			{Version: 5, Direction: storage.HistoryUp},
+		},
+	}}
+
+	m, err := New(context.Background(), lgr, &TestFactory{storages: []storage.Storager{s}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gaps, err := m.Gaps(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gaps) != 1 || len(gaps[0].OutOfOrder) != 1 || gaps[0].OutOfOrder[0].Version != 4 ||
+		len(gaps[0].Missing) != 1 || gaps[0].Missing[0] != 2 {
+		t.Fatalf("unexpected gaps: %+v", gaps)
+	}
+
+	versions := func(migrations []source.Migration) []uint {
+		v := make([]uint, 0, len(migrations))
+		for _, migration := range migrations {
+			v = append(v, migration.Version)
+		}
+		return v
+	}
+	steps := func() []uint {
+		plan, err := m.Plan(context.Background(), Up)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v := make([]uint, 0, len(plan.Storages[0].Steps))
+		for _, step := range plan.Storages[0].Steps {
+			v = append(v, step.Version)
+		}
+		return v
+	}
+
+	report, err := m.Status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status := report.Storages[0]; !slices.Equal(versions(status.Applied), []uint{1, 3, 5}) ||
+		!slices.Equal(versions(status.Pending), []uint{4, 6}) {
+		t.Fatalf("expected the out-of-order migration to be pending: %+v", status)
+	}
+	if got := steps(); !slices.Equal(got, []uint{6}) {
+		t.Fatalf("expected the out-of-order migration to be skipped by the plan, got: %v", got)
+	}
+	m.SetAllowOutOfOrder(true)
+	if got := steps(); !slices.Equal(got, []uint{4, 6}) {
+		t.Fatalf("expected the out-of-order migration to be planned, got: %v", got)
+	}
+	m.SetAllowOutOfOrder(false)
+
+	dirty := &DirtyStorage{HistoryStorage: HistoryStorage{TestStorage: TestStorage{version: 5, migrations: s.migrations}}}
+	dm, err := New(context.Background(), lgr, &TestFactory{storages: []storage.Storager{dirty}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report, err = dm.Status(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if status := report.Storages[0]; !slices.Equal(versions(status.Applied), []uint{1, 3, 4}) ||
+		!slices.Equal(versions(status.Pending), []uint{5, 6}) {
+		t.Fatalf("expected the dirty migration to be pending: %+v", status)
+	}
+
+	if err = m.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.applied) != 0 {
+		t.Fatalf("expected out-of-order migrations to be skipped, applied: %v", s.applied)
+	}
+
+	m.SetAllowOutOfOrder(true)
+	if err = m.Up(context.Background()); err != nil {
 		t.Fatal(err)
 	}
+	if len(s.applied) != 1 || s.applied[0] != 4 {
+		t.Fatalf("expected the out-of-order migration to be applied, applied: %v", s.applied)
+	}
+
+	if gaps, err = m.Gaps(context.Background()); err != nil || len(gaps[0].OutOfOrder) != 0 {
+		t.Fatalf("unexpected gaps: %+v, %v", gaps, err)
+	}
+}
+
+func TestMigrate_Repair(t *testing.T) {
+	out, cancel, err := logger.NewOutput(loggerenum.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel()
+
+	lgr, cancel, err := logger.NewLogrus(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel()
+
+	migrations := []source.Migration{
+		{Version: 1, Name: "first", Up: "1_first.up.sql", Down: "1_first.down.sql"},
+		{Version: 2, Name: "second", Up: "2_second.up.sql", Down: "2_second.down.sql"},
+		{Version: 3, Name: "third", Up: "3_third.up.sql", Down: "3_third.down.sql"},
+	}
+	newStorage := func(version uint, entries ...storage.HistoryEntry) *DirtyStorage {
+		return &DirtyStorage{HistoryStorage: HistoryStorage{
+			TestStorage: TestStorage{version: version, migrations: migrations},
+			entries:     entries,
+		}}
+	}
+
+	tests := []struct {
+		name    string
+		policy  RepairPolicy
+		env     string
+		storage *DirtyStorage
+		want    Repair
+		down    bool
+		wantErr bool
+	}{
+		{
+			name:    "report",
+			policy:  RepairReport,
+			storage: newStorage(3, storage.HistoryEntry{Version: 3, Direction: storage.HistoryUp, Error: "syntax error"}),
+			want:    Repair{Version: 3, Direction: storage.HistoryUp, Error: "syntax error", Target: 2, Action: RepairReport},
+		},
+		{
+			name:    "force",
+			policy:  RepairForce,
+			storage: newStorage(3, storage.HistoryEntry{Version: 3, Direction: storage.HistoryUp, Error: "syntax error"}),
+			want:    Repair{Version: 3, Direction: storage.HistoryUp, Error: "syntax error", Target: 2, Action: RepairForce},
+		},
+		{
+			name:    "down",
+			policy:  RepairDown,
+			storage: newStorage(1),
+			want:    Repair{Version: 1, Direction: storage.HistoryUp, Target: -1, Action: RepairDown},
+			down:    true,
+		},
+		{
+			name:    "failed down is forced back",
+			policy:  RepairDown,
+			storage: newStorage(2, storage.HistoryEntry{Version: 3, Direction: storage.HistoryDown, Error: "lock timeout"}),
+			want:    Repair{Version: 2, Direction: storage.HistoryDown, Error: "lock timeout", Target: 3, Action: RepairForce},
+		},
+		{
+			name:    "down rejected in production",
+			policy:  RepairDown,
+			env:     "production",
+			storage: newStorage(3),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := New(context.Background(), lgr, &TestFactory{storages: []storage.Storager{tt.storage}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = m.SetRepairPolicy(tt.policy); err != nil {
+				t.Fatal(err)
+			}
+			m.DownPolicy().Env = tt.env
+
+			repairs, err := m.Repair(context.Background())
+			if tt.wantErr {
+				if err == nil || tt.storage.fixed {
+					t.Fatalf("expected the repair to be rejected, err: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(repairs) != 1 {
+				t.Fatalf("expected a single repair, got: %+v", repairs)
+			}
+
+			got := repairs[0]
+			if got.Version != tt.want.Version || got.Direction != tt.want.Direction || got.Error != tt.want.Error ||
+				got.Target != tt.want.Target || got.Action != tt.want.Action || got.Migration.Name == "" {
+				t.Fatalf("unexpected repair: %+v, want: %+v", got, tt.want)
+			}
+			if repaired := tt.want.Action != RepairReport; tt.storage.fixed != repaired ||
+				(repaired && (tt.storage.target != tt.want.Target || tt.storage.down != tt.down)) {
+				t.Fatalf("unexpected storage state: %+v", tt.storage)
+			}
+
+			if repairs, err = m.Repair(context.Background()); err != nil || (tt.storage.fixed && len(repairs) != 0) {
+				t.Fatalf("expected the repaired storage to be clean: %+v, %v", repairs, err)
+			}
+		})
+	}
+
+	m, err := New(context.Background(), lgr, &TestFactory{storages: []storage.Storager{newStorage(3)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = m.SetRepairPolicy("rollback"); !errors.Is(err, ErrUnknownRepairPolicy) {
+		t.Fatalf("expected ErrUnknownRepairPolicy, got: %v", err)
+	}
 }
@@ -0,0 +1,116 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"github.com/Borislavv/go-logger/pkg/logger"
+	"github.com/Borislavv/go-migrate/pkg/migrate/source"
+	"github.com/Borislavv/go-migrate/pkg/migrate/storage"
+)
+
+var ErrUnknownDirection = errors.New("unknown migrations direction")
+
+type Direction string
+
+const (
+	Up   Direction = "up"
+	Down Direction = "down"
+)
+
+// Step is a single migration file which would be executed.
+type Step struct {
+	Version uint   `json:"version"`
+	Name    string `json:"name"`
+	// File is the path of the migration in the migrations fs, empty when the migration has no file for the direction.
+	File     string `json:"file"`
+	Checksum string `json:"checksum,omitempty"`
+	Content  string `json:"content,omitempty"`
+}
+
+// StoragePlan is the ordered list of steps which would be executed on a single storage.
+type StoragePlan struct {
+	Storage string `json:"storage"`
+	Version uint   `json:"version"`
+	Dirty   bool   `json:"dirty"`
+	Steps   []Step `json:"steps"`
+}
+
+// Plan is the set of steps which would be executed on each storage by Up or Down.
+type Plan struct {
+	Direction Direction     `json:"direction"`
+	Storages  []StoragePlan `json:"storages"`
+}
+
+// Plan resolves the current version of each storage and returns the ordered migration files
+// (with contents and checksums) which Up or Down would execute, the schema is not touched.
+// The out-of-order migrations are planned for Up only when they're allowed (see SetAllowOutOfOrder).
+// A dirty storage is reported with no steps, since both directions fail with ErrDirty until it's repaired (see Repair).
+func (m *Migrate) Plan(ctx context.Context, direction Direction) (*Plan, error) {
+	if direction != Up && direction != Down {
+		return nil, ErrUnknownDirection
+	}
+
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	plan := &Plan{Direction: direction, Storages: make([]StoragePlan, 0, len(m.storages))}
+	for _, s := range m.storages {
+		storagePlan, err := m.plan(ctx, s, direction)
+		if err != nil {
+			return nil, m.logger.Fatal(ctx, errors.New("migrations: [storage: "+s.Name()+", action: Plan]: error occurred while building plan"), logger.Fields{
+				"err":     err.Error(),
+				"storage": s.Name(),
+			})
+		}
+		plan.Storages = append(plan.Storages, storagePlan)
+	}
+
+	return plan, nil
+}
+
+func (m *Migrate) plan(ctx context.Context, s storage.Storager, direction Direction) (StoragePlan, error) {
+	status, err := m.status(ctx, s)
+	if err != nil {
+		return StoragePlan{}, err
+	}
+
+	storagePlan := StoragePlan{Storage: s.Name(), Version: status.Version, Dirty: status.Dirty, Steps: []Step{}}
+	if status.Dirty {
+		return storagePlan, nil
+	}
+
+	var migrations []source.Migration
+	if direction == Up {
+		for _, migration := range status.Pending {
+			// the out-of-order migrations are below the current version
+			if migration.Version < status.Version && !m.cfg.AllowOutOfOrder {
+				continue
+			}
+			migrations = append(migrations, migration)
+		}
+	} else {
+		for i := len(status.Applied) - 1; i >= 0; i-- {
+			migrations = append(migrations, status.Applied[i])
+		}
+	}
+
+	for _, migration := range migrations {
+		step := Step{Version: migration.Version, Name: migration.Name, File: migration.Up}
+		if direction == Down {
+			step.File = migration.Down
+		}
+
+		if step.File != "" {
+			content, err := s.ReadMigration(step.File)
+			if err != nil {
+				return StoragePlan{}, err
+			}
+			step.Content = string(content)
+			step.Checksum = source.Checksum(content)
+		}
+
+		storagePlan.Steps = append(storagePlan.Steps, step)
+	}
+
+	return storagePlan, nil
+}
@@ -0,0 +1,66 @@
+package migrate
+
+import (
+	"errors"
+	"github.com/kelseyhightower/envconfig"
+	"strings"
+)
+
+var (
+	ErrFailedLoadDownPolicy         = errors.New("failed to load down policy")
+	ErrDownIsDisabledInProduction   = errors.New("down migrations are disabled in production environment")
+	ErrDownConfirmationMismatch     = errors.New("down migrations require a valid confirmation token")
+	ErrDownStepsLimitExceeded       = errors.New("down migrations exceed the allowed max steps limit")
+	ErrDownFullRollbackIsNotAllowed = errors.New("full rollback is not allowed while max steps limit is set, use Steps instead")
+)
+
+// DownPolicy describes safeguards checked before any rollback is invoked on storages.
+type DownPolicy struct {
+	// Env is the current environment name, down migrations are disabled in "production" (or "prod") by default.
+	Env string `envconfig:"MIGRATIONS_ENV"`
+	// AllowInProduction explicitly enables down migrations in production environment.
+	AllowInProduction bool `envconfig:"MIGRATIONS_DOWN_ALLOW_IN_PRODUCTION" default:"false"`
+	// ConfirmationToken is a token which must be supplied as Confirmation to run down migrations (skipped when empty).
+	ConfirmationToken string `envconfig:"MIGRATIONS_DOWN_CONFIRMATION_TOKEN"`
+	// Confirmation is a token supplied by the operator.
+	Confirmation string `envconfig:"MIGRATIONS_DOWN_CONFIRMATION"`
+	// MaxSteps limits the number of migrations which may be rolled back at once (unlimited when zero).
+	MaxSteps int `envconfig:"MIGRATIONS_DOWN_MAX_STEPS" default:"0"`
+}
+
+func LoadDownPolicy() (*DownPolicy, error) {
+	policy := new(DownPolicy)
+	if err := envconfig.Process("", policy); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// IsProduction reports whether the policy environment is a production one.
+func (p *DownPolicy) IsProduction() bool {
+	env := strings.ToLower(strings.TrimSpace(p.Env))
+	return env == "production" || env == "prod"
+}
+
+// Check validates whether rolling back the given number of steps is allowed,
+// a negative steps value means the full rollback (Down).
+func (p *DownPolicy) Check(steps int) error {
+	if p.IsProduction() && !p.AllowInProduction {
+		return ErrDownIsDisabledInProduction
+	}
+
+	if p.ConfirmationToken != "" && p.Confirmation != p.ConfirmationToken {
+		return ErrDownConfirmationMismatch
+	}
+
+	if p.MaxSteps > 0 {
+		if steps < 0 {
+			return ErrDownFullRollbackIsNotAllowed
+		}
+		if steps > p.MaxSteps {
+			return ErrDownStepsLimitExceeded
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,218 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Borislavv/go-logger/pkg/logger"
+	"github.com/Borislavv/go-migrate/pkg/migrate/source"
+	"github.com/Borislavv/go-migrate/pkg/migrate/storage"
+	"github.com/Borislavv/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database"
+)
+
+var (
+	ErrUnknownRepairPolicy  = errors.New("unknown repair policy")
+	ErrRepairIsNotSupported = errors.New("storage does not support running the down file on repair")
+)
+
+// RepairPolicy defines what Repair does with a dirty storage.
+type RepairPolicy string
+
+const (
+	// RepairReport only reports the failed migration, the storage is left dirty.
+	RepairReport RepairPolicy = "report"
+	// RepairForce forces the previous clean version without running anything.
+	RepairForce RepairPolicy = "force"
+	// RepairDown runs the down file of the failed up migration (checked against the down policy) and forces
+	// the previous clean version, a failed down migration is forced back to its version as under RepairForce.
+	RepairDown RepairPolicy = "down"
+)
+
+// Repair describes the dirty state of a single storage and what was done with it.
+type Repair struct {
+	Storage string `json:"storage"`
+	// Version is the dirty version.
+	Version uint `json:"version"`
+	// Migration is the failed one, it's resolved from the history, otherwise the failed up of the dirty version is assumed.
+	Migration source.Migration `json:"migration"`
+	// Direction of the failed migration, storage.HistoryUp or storage.HistoryDown.
+	Direction string `json:"direction"`
+	// Error recorded into the history, empty when it's unknown.
+	Error string `json:"error,omitempty"`
+	// Target is the clean version, database.NilVersion (-1) means no version.
+	Target int `json:"target"`
+	// Action is the applied policy, RepairReport means the storage was left dirty.
+	Action RepairPolicy `json:"action"`
+}
+
+// SetRepairPolicy overrides the repair policy loaded from env (MIGRATIONS_REPAIR_POLICY).
+func (m *Migrate) SetRepairPolicy(policy RepairPolicy) error {
+	switch policy {
+	case RepairReport, RepairForce, RepairDown:
+		m.cfg.RepairPolicy = policy
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownRepairPolicy, policy)
+	}
+}
+
+// Repair inspects each storage and reports the failed migration of the dirty ones, depending on the repair policy
+// the dirty storages are forced back to the previous clean version (optionally running the down file first).
+// The repairs are recorded into the history (see storage.HistoryRepair) by storages which implement storage.Repairer.
+func (m *Migrate) Repair(ctx context.Context) ([]Repair, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	unlock, err := m.lock(ctx, "Repair", m.storages...)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	repairs := make([]Repair, 0)
+	for _, s := range m.storages {
+		prefix := "migrations: [storage: " + s.Name() + ", action: Repair]: "
+
+		repair, err := m.inspect(ctx, s)
+		if err != nil {
+			return repairs, m.logger.Fatal(ctx, errors.New(prefix+"error occurred while inspecting dirty state"), logger.Fields{
+				"err":     err.Error(),
+				"storage": s.Name(),
+			})
+		}
+		if repair == nil {
+			continue
+		}
+
+		fields := logger.Fields{
+			"storage":   s.Name(),
+			"version":   repair.Version,
+			"migration": repair.Migration.Version,
+			"direction": repair.Direction,
+			"error":     repair.Error,
+			"target":    repair.Target,
+		}
+
+		if err = m.repair(ctx, s, repair); err != nil {
+			fields["err"] = err.Error()
+			return repairs, m.logger.Fatal(ctx, errors.New(prefix+"error occurred while repairing dirty state"), fields)
+		}
+		repairs = append(repairs, *repair)
+
+		fields["action"] = repair.Action
+		if repair.Action == RepairReport {
+			m.logger.WarningMsg(ctx, prefix+"storage is dirty, see MIGRATIONS_REPAIR_POLICY", fields)
+			continue
+		}
+		m.logger.InfoMsg(ctx, prefix+"dirty state successfully repaired", fields)
+	}
+
+	return repairs, nil
+}
+
+// inspect resolves the failed migration of the dirty storage, nil is returned when the storage is clean.
+func (m *Migrate) inspect(ctx context.Context, s storage.Storager) (*Repair, error) {
+	version, dirty, err := s.Version(ctx)
+	if err != nil {
+		if errors.Is(err, migrate.ErrNilVersion) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if !dirty {
+		return nil, nil
+	}
+
+	migrations, err := s.Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	repair := &Repair{
+		Storage:   s.Name(),
+		Version:   version,
+		Migration: source.Migration{Version: version},
+		Direction: storage.HistoryUp,
+		Target:    previous(migrations, version),
+	}
+
+	if historian, ok := s.(storage.Historian); ok {
+		entries, err := historian.History(ctx)
+		if err != nil && !errors.Is(err, storage.ErrHistoryIsNotEnabled) {
+			return nil, err
+		}
+
+		for i := len(entries) - 1; i >= 0; i-- {
+			entry := entries[i]
+			if entry.Direction == storage.HistoryRepair {
+				continue
+			}
+			if entry.Error == "" {
+				break
+			}
+
+			switch {
+			case entry.Direction == storage.HistoryUp && entry.Version == version:
+				repair.Error = entry.Error
+			case entry.Direction == storage.HistoryDown && entry.Version > version:
+				// the dirty version of a failed down is the previous one, the clean state is the one before the down
+				repair.Migration.Version, repair.Direction, repair.Error = entry.Version, storage.HistoryDown, entry.Error
+				repair.Target = int(entry.Version)
+			}
+			break
+		}
+	}
+
+	for _, migration := range migrations {
+		if migration.Version == repair.Migration.Version {
+			repair.Migration = migration
+		}
+	}
+
+	return repair, nil
+}
+
+// repair applies the repair policy to the dirty storage and sets the executed action.
+func (m *Migrate) repair(ctx context.Context, s storage.Storager, repair *Repair) error {
+	action := m.cfg.RepairPolicy
+	if action == RepairDown && repair.Direction == storage.HistoryDown {
+		action = RepairForce
+	}
+
+	repairer, ok := s.(storage.Repairer)
+	switch action {
+	case RepairForce:
+		if ok {
+			if err := repairer.Repair(ctx, repair.Migration, repair.Target, false); err != nil {
+				return err
+			}
+		} else if err := s.Force(ctx, repair.Target); err != nil {
+			return err
+		}
+	case RepairDown:
+		if !ok {
+			return ErrRepairIsNotSupported
+		}
+		if err := m.downPolicy.Check(1); err != nil {
+			return err
+		}
+		if err := repairer.Repair(ctx, repair.Migration, repair.Target, true); err != nil {
+			return err
+		}
+	}
+
+	repair.Action = action
+	return nil
+}
+
+// previous returns the highest available version below the given one or database.NilVersion.
+func previous(migrations []source.Migration, version uint) int {
+	target := database.NilVersion
+	for _, migration := range migrations {
+		if migration.Version < version {
+			target = int(migration.Version)
+		}
+	}
+	return target
+}
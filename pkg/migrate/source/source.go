@@ -0,0 +1,68 @@
+package source
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	migratesource "github.com/golang-migrate/migrate/v4/source"
+	"io/fs"
+	"path"
+	"sort"
+)
+
+// Migration describes a single versioned migration found in the migrations directory.
+type Migration struct {
+	Version uint   `json:"version"`
+	Name    string `json:"name"`
+	Up      string `json:"up,omitempty"`
+	Down    string `json:"down,omitempty"`
+}
+
+// List walks the migrations directory of fsys and returns migrations ordered by version,
+// files which do not match the {version}_{name}.{up|down}.{ext} pattern are skipped.
+func List(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[uint]*Migration, len(entries)/2)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		parsed, err := migratesource.Parse(entry.Name())
+		if err != nil {
+			continue
+		}
+
+		m, ok := byVersion[parsed.Version]
+		if !ok {
+			m = &Migration{Version: parsed.Version, Name: parsed.Identifier}
+			byVersion[parsed.Version] = m
+		}
+
+		switch parsed.Direction {
+		case migratesource.Up:
+			m.Up = path.Join(dir, parsed.Raw)
+		case migratesource.Down:
+			m.Down = path.Join(dir, parsed.Raw)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Checksum returns the hex encoded SHA-256 of the migration content.
+func Checksum(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,33 @@
+package source
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestList(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/2_add_email.up.sql":      {Data: []byte("ALTER TABLE users ADD email TEXT;")},
+		"migrations/1_create_users.up.sql":   {Data: []byte("CREATE TABLE users (id INT);")},
+		"migrations/1_create_users.down.sql": {Data: []byte("DROP TABLE users;")},
+		"migrations/README.md":               {Data: []byte("not a migration")},
+	}
+
+	migrations, err := List(fsys, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(migrations) != 2 {
+		t.Fatalf("expected 2 migrations, got %d", len(migrations))
+	}
+
+	first, second := migrations[0], migrations[1]
+	if first.Version != 1 || first.Name != "create_users" ||
+		first.Up != "migrations/1_create_users.up.sql" || first.Down != "migrations/1_create_users.down.sql" {
+		t.Fatalf("unexpected first migration: %+v", first)
+	}
+	if second.Version != 2 || second.Name != "add_email" || second.Down != "" {
+		t.Fatalf("unexpected second migration: %+v", second)
+	}
+}
@@ -0,0 +1,86 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"github.com/Borislavv/go-logger/pkg/logger"
+	"github.com/Borislavv/go-migrate/pkg/migrate/source"
+	"github.com/Borislavv/go-migrate/pkg/migrate/storage"
+	"github.com/Borislavv/migrate/v4"
+)
+
+// Status describes the migrations state of a single storage.
+type Status struct {
+	Storage string `json:"storage"`
+	// Version is the current version (zero when nothing was applied yet).
+	Version uint `json:"version"`
+	// Dirty is true when the migration of the current version failed halfway, it's reported as pending then.
+	Dirty   bool               `json:"dirty"`
+	Applied []source.Migration `json:"applied"`
+	// Pending includes the out-of-order migrations below the current version which were never applied (see Gaps).
+	Pending []source.Migration `json:"pending"`
+}
+
+// Report describes the migrations state of all storages.
+type Report struct {
+	Storages []Status `json:"storages"`
+}
+
+// Status returns the current version, dirty flag, applied and pending migrations of each storage.
+func (m *Migrate) Status(ctx context.Context) (*Report, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	report := &Report{Storages: make([]Status, 0, len(m.storages))}
+	for _, s := range m.storages {
+		status, err := m.status(ctx, s)
+		if err != nil {
+			return nil, m.logger.Fatal(ctx, errors.New("migrations: [storage: "+s.Name()+", action: Status]: error occurred while fetching status"), logger.Fields{
+				"err":     err.Error(),
+				"storage": s.Name(),
+			})
+		}
+		report.Storages = append(report.Storages, status)
+	}
+
+	return report, nil
+}
+
+func (m *Migrate) status(ctx context.Context, s storage.Storager) (Status, error) {
+	status := Status{Storage: s.Name(), Applied: []source.Migration{}, Pending: []source.Migration{}}
+
+	applied := true
+	version, dirty, err := s.Version(ctx)
+	if err != nil {
+		if !errors.Is(err, migrate.ErrNilVersion) {
+			return status, err
+		}
+		applied = false
+	}
+	status.Version, status.Dirty = version, dirty
+
+	migrations, err := s.Migrations()
+	if err != nil {
+		return status, err
+	}
+
+	gap, err := m.gap(ctx, s)
+	if err != nil {
+		return status, err
+	}
+	outOfOrder := make(map[uint]struct{}, len(gap.OutOfOrder))
+	for _, migration := range gap.OutOfOrder {
+		outOfOrder[migration.Version] = struct{}{}
+	}
+
+	for _, migration := range migrations {
+		_, skipped := outOfOrder[migration.Version]
+		if applied && migration.Version <= version && !skipped && !(dirty && migration.Version == version) {
+			status.Applied = append(status.Applied, migration)
+		} else {
+			status.Pending = append(status.Pending, migration)
+		}
+	}
+
+	return status, nil
+}
@@ -0,0 +1,42 @@
+package storage
+
+import (
+	"context"
+	"github.com/Borislavv/go-migrate/pkg/migrate/source"
+	"github.com/golang-migrate/migrate/v4/database"
+	migratesource "github.com/golang-migrate/migrate/v4/source"
+	"os"
+	"time"
+)
+
+// Applier is implemented by storages which can execute the up file of a single migration without moving
+// the version, it's used to apply out-of-order migrations (lower than the current version).
+type Applier interface {
+	Apply(ctx context.Context, migration source.Migration) error
+}
+
+// Apply executes the migration content through the migrate driver under its lock without moving the version,
+// the migration is recorded into the history (if any) as an up one.
+func Apply(ctx context.Context, d database.Driver, history History, migration source.Migration, content []byte) error {
+	if err := d.Lock(); err != nil {
+		return err
+	}
+	defer func() { _ = d.Unlock() }()
+
+	entry := HistoryEntry{
+		Version:   migration.Version,
+		Name:      migration.Name,
+		Direction: HistoryUp,
+		Checksum:  source.Checksum(content),
+		StartedAt: time.Now(),
+	}
+
+	err := runMigration(d, migration.Version, migratesource.Up, content)
+	if history != nil {
+		host, _ := os.Hostname()
+		if appendErr := appendEntry(ctx, history, entry, host, err); err == nil {
+			err = appendErr
+		}
+	}
+	return err
+}
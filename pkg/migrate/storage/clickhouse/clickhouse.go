@@ -0,0 +1,320 @@
+package clickhouse
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/Borislavv/go-migrate/pkg/migrate/source"
+	"github.com/Borislavv/go-migrate/pkg/migrate/storage"
+	"github.com/Borislavv/migrate/v4"
+	"github.com/Borislavv/migrate/v4/database/clickhouse"
+	_ "github.com/ClickHouse/clickhouse-go"
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"io/fs"
+	"net"
+	"net/url"
+	"strings"
+)
+
+const (
+	DriverName = "clickhouse"
+	// MigrationsExt is the extension of the migration files.
+	MigrationsExt = "sql"
+)
+
+// historyDDL creates the history table, see storage.SQLHistoryDDL and historyTableDDL.
+const historyDDL = `CREATE TABLE IF NOT EXISTS %%s%s (
+	version     Int64,
+	name        String,
+	direction   String,
+	checksum    String,
+	started_at  DateTime64(6, 'UTC'),
+	finished_at DateTime64(6, 'UTC'),
+	duration_ms Int64,
+	host        String,
+	app_version String,
+	error       String
+) ENGINE = %s ORDER BY started_at`
+
+type ClickHouse struct {
+	name    string
+	db      *sql.DB
+	cfg     Configurator
+	fs      fs.FS
+	history storage.History
+}
+
+func New(ctx context.Context, cfg Configurator, fsys fs.FS) (*ClickHouse, error) {
+	db, err := sql.Open(DriverName, dsn(cfg))
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		<-ctx.Done()
+		_ = db.Close()
+	}()
+
+	return NewWithDB(ctx, db, cfg, fsys)
+}
+
+// NewWithDB makes the storage on top of the existing connection pool, which is owned (and closed) by the caller.
+func NewWithDB(ctx context.Context, db *sql.DB, cfg Configurator, fsys fs.FS) (*ClickHouse, error) {
+	if err := storage.Connect(ctx, cfg.GetClickHouseConnectRetries(), cfg.GetClickHouseConnectTimeout(), db.PingContext); err != nil {
+		return nil, err
+	}
+
+	var h storage.History
+	if table := cfg.GetClickHouseMigrationsHistoryTable(); storage.IsHistoryEnabled(table) {
+		h = storage.NewSQLHistory(db, table, historyTableDDL(cfg), false)
+	}
+
+	return &ClickHouse{
+		name:    DriverName,
+		db:      db,
+		cfg:     cfg,
+		fs:      fsys,
+		history: h,
+	}, nil
+}
+
+func (m *ClickHouse) Name() string {
+	return m.name
+}
+
+func (m *ClickHouse) Up(ctx context.Context) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	s, err := m.migrate(ctx)
+	if err != nil {
+		return err
+	}
+
+	return storage.Run(ctx, s, s.Up)
+}
+
+func (m *ClickHouse) Down(ctx context.Context) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	s, err := m.migrate(ctx)
+	if err != nil {
+		return err
+	}
+
+	return storage.Run(ctx, s, s.Down)
+}
+
+func (m *ClickHouse) Migrate(ctx context.Context, version uint) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	s, err := m.migrate(ctx)
+	if err != nil {
+		return err
+	}
+
+	return storage.Run(ctx, s, func() error { return s.Migrate(version) })
+}
+
+func (m *ClickHouse) Steps(ctx context.Context, n int) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	s, err := m.migrate(ctx)
+	if err != nil {
+		return err
+	}
+
+	return storage.Run(ctx, s, func() error { return s.Steps(n) })
+}
+
+func (m *ClickHouse) Force(ctx context.Context, n int) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	s, err := m.migrate(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err = s.Force(n); err != nil {
+		return err
+	}
+
+	return ctx.Err()
+}
+
+func (m *ClickHouse) Version(ctx context.Context) (version uint, dirty bool, err error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	s, err := m.migrate(ctx)
+	if err != nil {
+		return 0, true, err
+	}
+
+	return s.Version()
+}
+
+// Migrations returns all migrations available in the migrations fs and the registered Go ones ordered by version.
+func (m *ClickHouse) Migrations() ([]source.Migration, error) {
+	migrations, err := source.List(m.fs, m.cfg.GetClickHouseMigrationsDir())
+	if err != nil {
+		return nil, err
+	}
+
+	return storage.WithGoMigrations(m.name, migrations)
+}
+
+// ReadMigration returns the content of the migration file by its path in the migrations fs (or of the Go migration).
+func (m *ClickHouse) ReadMigration(path string) ([]byte, error) {
+	if content, ok := storage.ReadGoMigration(m.name, path); ok {
+		return content, nil
+	}
+	return fs.ReadFile(m.fs, path)
+}
+
+// History returns the executed migrations recorded into CLICKHOUSE_MIGRATIONS_HISTORY_TABLE,
+// the history is disabled when it's empty or storage.HistoryDisabled, see storage.Historian.
+func (m *ClickHouse) History(ctx context.Context) ([]storage.HistoryEntry, error) {
+	if m.history == nil {
+		return nil, storage.ErrHistoryIsNotEnabled
+	}
+
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	return m.history.Entries(ctx)
+}
+
+// Apply executes the up file of the migration without moving the version, see storage.Applier.
+func (m *ClickHouse) Apply(ctx context.Context, migration source.Migration) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	content, err := m.ReadMigration(migration.Up)
+	if err != nil {
+		return err
+	}
+
+	d, err := m.driver(ctx)
+	if err != nil {
+		return err
+	}
+
+	return storage.Apply(ctx, d, m.history, migration, content)
+}
+
+// Repair executes the down file of the failed migration (if requested) and forces the clean version,
+// see storage.Repairer.
+func (m *ClickHouse) Repair(ctx context.Context, failed source.Migration, version int, down bool) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	var content []byte
+	if down && failed.Down != "" {
+		var err error
+		if content, err = m.ReadMigration(failed.Down); err != nil {
+			return err
+		}
+	}
+
+	d, err := m.driver(ctx)
+	if err != nil {
+		return err
+	}
+
+	return storage.Repair(ctx, d, m.history, failed, content, version)
+}
+
+// withTimeout limits ctx by the configured migrations timeout (if any).
+func (m *ClickHouse) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return storage.WithTimeout(ctx, m.cfg.GetClickHouseMigrationsTimeout())
+}
+
+func (m *ClickHouse) migrate(ctx context.Context) (*migrate.Migrate, error) {
+	migrations, err := m.Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := m.driver(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	src, err := iofs.New(m.fs, m.cfg.GetClickHouseMigrationsDir())
+	if err != nil {
+		return nil, fmt.Errorf("could not open ClickHouse migrations fs: %w", err)
+	}
+
+	s, err := migrate.NewWithInstance("iofs", storage.GoSource(src, m.name, migrations), DriverName, storage.Record(ctx, d, m.history, migrations))
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// driver makes the migrate database driver, it must not be closed as it would close the shared pool.
+func (m *ClickHouse) driver(ctx context.Context) (database.Driver, error) {
+	if m.db == nil {
+		return nil, errors.New("the underlying database pointer is not initialized, you need to call the 'New' method first")
+	}
+
+	d, err := clickhouse.WithInstance(m.db, driverConfig(m.cfg))
+	if err != nil {
+		return nil, err
+	}
+
+	return storage.GoDriver(ctx, d, m.name, storage.HandleExecutor(m.db)), nil
+}
+
+// driverConfig returns the config of the migrate database driver which manages the version table.
+func driverConfig(cfg Configurator) *clickhouse.Config {
+	return &clickhouse.Config{
+		DatabaseName:          cfg.GetClickHouseDatabase(),
+		ClusterName:           cfg.GetClickHouseClusterName(),
+		MigrationsTable:       cfg.GetClickHouseMigrationsTable(),
+		MigrationsTableEngine: cfg.GetClickHouseMigrationsTableEngine(),
+		MultiStatementEnabled: cfg.IsClickHouseMultiStatementEnabled(),
+	}
+}
+
+// historyTableDDL returns the ddl of the history table created ON CLUSTER (if CLICKHOUSE_CLUSTER_NAME is set) with
+// CLICKHOUSE_MIGRATIONS_HISTORY_TABLE_ENGINE, which must be of the MergeTree family (e.g. ReplicatedMergeTree).
+func historyTableDDL(cfg Configurator) string {
+	var cluster string
+	if name := cfg.GetClickHouseClusterName(); name != "" {
+		cluster = " ON CLUSTER " + name
+	}
+	// the ddl is formatted with the table name once more by storage.SQLHistory
+	escape := func(s string) string { return strings.ReplaceAll(s, "%", "%%") }
+
+	return fmt.Sprintf(historyDDL, escape(cluster), escape(cfg.GetClickHouseMigrationsHistoryTableEngine()))
+}
+
+// dsn returns CLICKHOUSE_DSN as is or builds the one with escaped credentials and extra params (e.g. secure, skip_verify).
+func dsn(cfg Configurator) string {
+	if cfg.GetClickHouseDSN() != "" {
+		return cfg.GetClickHouseDSN()
+	}
+
+	query := url.Values{}
+	for key, value := range cfg.GetClickHouseParams() {
+		query.Set(key, value)
+	}
+	query.Set("username", cfg.GetClickHouseUsername())
+	query.Set("password", cfg.GetClickHousePassword())
+	query.Set("database", cfg.GetClickHouseDatabase())
+
+	return (&url.URL{
+		Scheme:   "tcp",
+		Host:     net.JoinHostPort(cfg.GetClickHouseHost(), cfg.GetClickHousePort()),
+		RawQuery: query.Encode(),
+	}).String()
+}
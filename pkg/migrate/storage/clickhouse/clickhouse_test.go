@@ -0,0 +1,86 @@
+package clickhouse
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestDSN(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      *Config
+		expected string
+	}{
+		{
+			name: "escaped credentials and params",
+			cfg: &Config{
+				ClickHouseHost:     "ch.local",
+				ClickHousePort:     "9440",
+				ClickHouseUsername: "app@prod",
+				ClickHousePassword: "p@ss&w/rd?",
+				ClickHouseDatabase: "events",
+				ClickHouseParams:   map[string]string{"secure": "true", "skip_verify": "false"},
+			},
+			expected: "tcp://ch.local:9440?database=events&password=p%40ss%26w%2Frd%3F&secure=true&skip_verify=false&username=app%40prod",
+		},
+		{
+			name: "params don't override credentials",
+			cfg: &Config{
+				ClickHouseHost:     "ch.local",
+				ClickHousePort:     "9000",
+				ClickHouseUsername: "app",
+				ClickHouseDatabase: "events",
+				ClickHouseParams:   map[string]string{"username": "admin"},
+			},
+			expected: "tcp://ch.local:9000?database=events&password=&username=app",
+		},
+		{
+			name: "explicit dsn",
+			cfg: &Config{
+				ClickHouseHost: "ignored",
+				ClickHouseDSN:  "tcp://ch.local:9000?username=app&database=events",
+			},
+			expected: "tcp://ch.local:9000?username=app&database=events",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := dsn(tt.cfg); actual != tt.expected {
+				t.Fatalf("expected %s, got %s", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestDriverConfig(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ClickHouseDatabase = "events"
+	cfg.ClickHouseClusterName = "main"
+	cfg.ClickHouseMigrationsTableEngine = "ReplicatedMergeTree ORDER BY tuple()"
+
+	actual := driverConfig(cfg)
+	if actual.DatabaseName != "events" || actual.ClusterName != "main" || actual.MigrationsTable != "migration_versions" ||
+		actual.MigrationsTableEngine != "ReplicatedMergeTree ORDER BY tuple()" || !actual.MultiStatementEnabled {
+		t.Fatalf("unexpected driver config: %+v", actual)
+	}
+}
+
+func TestHistoryTableDDL(t *testing.T) {
+	cfg := DefaultConfig()
+	if ddl := historyTableDDL(cfg); !strings.HasPrefix(ddl, "CREATE TABLE IF NOT EXISTS %s (") ||
+		!strings.HasSuffix(ddl, ") ENGINE = MergeTree() ORDER BY started_at") {
+		t.Fatalf("unexpected ddl: %s", ddl)
+	}
+
+	cfg.ClickHouseClusterName = "main"
+	cfg.ClickHouseMigrationsHistoryTableEngine = "ReplicatedMergeTree('/clickhouse/tables/{shard}/%db', '{replica}')"
+
+	// the ddl is formatted with the table name by storage.SQLHistory
+	ddl := fmt.Sprintf(historyTableDDL(cfg), "migration_history")
+	if !strings.HasPrefix(ddl, "CREATE TABLE IF NOT EXISTS migration_history ON CLUSTER main (") ||
+		!strings.HasSuffix(ddl, ") ENGINE = ReplicatedMergeTree('/clickhouse/tables/{shard}/%db', '{replica}') ORDER BY started_at") {
+		t.Fatalf("unexpected ddl: %s", ddl)
+	}
+}
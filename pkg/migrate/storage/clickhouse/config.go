@@ -0,0 +1,134 @@
+package clickhouse
+
+import (
+	"github.com/Borislavv/go-migrate/pkg/migrate/storage"
+	"time"
+)
+
+type Configurator interface {
+	IsClickHouseMigrationsEnabled() bool
+	GetClickHouseUsername() string
+	GetClickHousePassword() string
+	GetClickHouseDatabase() string
+	GetClickHouseHost() string
+	GetClickHousePort() string
+	GetClickHouseDSN() string
+	GetClickHouseParams() map[string]string
+	GetClickHouseMigrationsTable() string
+	GetClickHouseMigrationsHistoryTable() string
+	GetClickHouseMigrationsTableEngine() string
+	GetClickHouseMigrationsHistoryTableEngine() string
+	GetClickHouseClusterName() string
+	IsClickHouseMultiStatementEnabled() bool
+	GetClickHouseConnectTimeout() time.Duration
+	GetClickHouseConnectRetries() int
+	GetClickHouseMigrationsDir() string
+	GetClickHouseMigrationsTimeout() time.Duration
+}
+
+type Config struct {
+	ClickHouseMigrationsEnabled            bool              `envconfig:"CLICKHOUSE_MIGRATIONS_ENABLED" default:"false"`
+	ClickHouseHost                         string            `envconfig:"CLICKHOUSE_HOST"`
+	ClickHousePort                         string            `envconfig:"CLICKHOUSE_PORT" default:"9000"`
+	ClickHouseUsername                     string            `envconfig:"CLICKHOUSE_LOGIN"`
+	ClickHousePassword                     string            `envconfig:"CLICKHOUSE_PASSWORD"`
+	ClickHouseDatabase                     string            `envconfig:"CLICKHOUSE_DATABASE"`
+	ClickHouseDSN                          string            `envconfig:"CLICKHOUSE_DSN"`
+	ClickHouseParams                       map[string]string `envconfig:"CLICKHOUSE_PARAMS"`
+	ClickHouseMigrationsTable              string            `envconfig:"CLICKHOUSE_MIGRATIONS_TABLE" default:"migration_versions"`
+	ClickHouseMigrationsHistoryTable       string            `envconfig:"CLICKHOUSE_MIGRATIONS_HISTORY_TABLE" default:"migration_history"`
+	ClickHouseMigrationsTableEngine        string            `envconfig:"CLICKHOUSE_MIGRATIONS_TABLE_ENGINE" default:"TinyLog"`
+	ClickHouseMigrationsHistoryTableEngine string            `envconfig:"CLICKHOUSE_MIGRATIONS_HISTORY_TABLE_ENGINE" default:"MergeTree()"`
+	ClickHouseClusterName                  string            `envconfig:"CLICKHOUSE_CLUSTER_NAME"`
+	ClickHouseMultiStatementEnabled        bool              `envconfig:"CLICKHOUSE_MULTI_STATEMENT_ENABLED" default:"true"`
+	ClickHouseConnectTimeout               time.Duration     `envconfig:"CLICKHOUSE_CONNECT_TIMEOUT" default:"0"`
+	ClickHouseConnectRetries               int               `envconfig:"CLICKHOUSE_CONNECT_RETRIES" default:"0"`
+	ClickHouseMigrationsDir                string            `envconfig:"CLICKHOUSE_MIGRATIONS_DIR" default:"migrations"`
+	ClickHouseMigrationsTimeout            time.Duration     `envconfig:"CLICKHOUSE_MIGRATIONS_TIMEOUT" default:"0"`
+}
+
+// DefaultConfig returns the config with the same defaults as the env one.
+func DefaultConfig() *Config {
+	return storage.SetDefaults(new(Config))
+}
+
+func Load() (*Config, error) {
+	return LoadWithPrefix("")
+}
+
+// LoadWithPrefix loads the config of a named storage instance, e.g. BILLING_CLICKHOUSE_HOST for the "BILLING" prefix.
+func LoadWithPrefix(prefix string) (*Config, error) {
+	return storage.LoadEnv[Config](prefix)
+}
+
+func (c *Config) IsClickHouseMigrationsEnabled() bool {
+	return c.ClickHouseMigrationsEnabled
+}
+
+func (c *Config) GetClickHouseHost() string {
+	return c.ClickHouseHost
+}
+
+func (c *Config) GetClickHousePort() string {
+	return c.ClickHousePort
+}
+
+func (c *Config) GetClickHouseUsername() string {
+	return c.ClickHouseUsername
+}
+
+func (c *Config) GetClickHousePassword() string {
+	return c.ClickHousePassword
+}
+
+func (c *Config) GetClickHouseDatabase() string {
+	return c.ClickHouseDatabase
+}
+
+func (c *Config) GetClickHouseDSN() string {
+	return c.ClickHouseDSN
+}
+
+func (c *Config) GetClickHouseParams() map[string]string {
+	return c.ClickHouseParams
+}
+
+func (c *Config) GetClickHouseMigrationsTable() string {
+	return c.ClickHouseMigrationsTable
+}
+
+func (c *Config) GetClickHouseMigrationsHistoryTable() string {
+	return c.ClickHouseMigrationsHistoryTable
+}
+
+func (c *Config) GetClickHouseMigrationsTableEngine() string {
+	return c.ClickHouseMigrationsTableEngine
+}
+
+func (c *Config) GetClickHouseMigrationsHistoryTableEngine() string {
+	return c.ClickHouseMigrationsHistoryTableEngine
+}
+
+func (c *Config) GetClickHouseClusterName() string {
+	return c.ClickHouseClusterName
+}
+
+func (c *Config) IsClickHouseMultiStatementEnabled() bool {
+	return c.ClickHouseMultiStatementEnabled
+}
+
+func (c *Config) GetClickHouseConnectTimeout() time.Duration {
+	return c.ClickHouseConnectTimeout
+}
+
+func (c *Config) GetClickHouseConnectRetries() int {
+	return c.ClickHouseConnectRetries
+}
+
+func (c *Config) GetClickHouseMigrationsDir() string {
+	return c.ClickHouseMigrationsDir
+}
+
+func (c *Config) GetClickHouseMigrationsTimeout() time.Duration {
+	return c.ClickHouseMigrationsTimeout
+}
@@ -0,0 +1,64 @@
+package clickhouse
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/Borislavv/go-migrate/pkg/migrate/storage"
+	"io/fs"
+)
+
+var (
+	ErrFailedLoadClickHouseConfig     = errors.New("failed to load ClickHouse config")
+	ErrFailedCreateInstanceClickHouse = errors.New("failed to create ClickHouse instance")
+)
+
+var registration = storage.Driver[Configurator, *ClickHouse]{
+	Name:    DriverName,
+	Load:    func(prefix string) (Configurator, error) { return LoadWithPrefix(prefix) },
+	Enabled: Configurator.IsClickHouseMigrationsEnabled,
+	Defaults: func(cfg Configurator) Configurator {
+		if c, ok := cfg.(*Config); ok && c != nil {
+			return storage.WithDefaults(c)
+		}
+		return cfg
+	},
+	New:       New,
+	Rename:    func(m *ClickHouse, name string) { m.name = name },
+	ErrLoad:   ErrFailedLoadClickHouseConfig,
+	ErrCreate: ErrFailedCreateInstanceClickHouse,
+}
+
+func init() {
+	storage.Register(DriverName, Make)
+}
+
+// Make loads the instance config from env and creates the storage, it's registered as the storage.Constructor.
+func Make(ctx context.Context, instance storage.Instance) (storage.Storager, error) {
+	return registration.Make(ctx, instance)
+}
+
+// WithConfig replaces the env configured storage by the given config. The omitted fields of a *Config get the env
+// defaults except booleans, so a hand-built &Config{} runs with ClickHouseMultiStatementEnabled=false (true by env),
+// start from DefaultConfig() to keep multi statement migrations.
+func WithConfig(cfg Configurator, fsys fs.FS) storage.Option {
+	return WithNamedConfig(DriverName, cfg, fsys)
+}
+
+// WithNamedConfig adds the named instance of the storage configured by the given config (see WithConfig for the defaults).
+func WithNamedConfig(name string, cfg Configurator, fsys fs.FS) storage.Option {
+	return registration.WithConfig(name, cfg, fsys)
+}
+
+// WithDB replaces the env configured storage by the one which uses the existing connection pool of the application
+// (see WithConfig for the defaults).
+func WithDB(db *sql.DB, cfg Configurator, fsys fs.FS) storage.Option {
+	return WithNamedDB(DriverName, db, cfg, fsys)
+}
+
+// WithNamedDB adds the named instance of the storage which uses the existing connection pool of the application.
+func WithNamedDB(name string, db *sql.DB, cfg Configurator, fsys fs.FS) storage.Option {
+	return registration.With(name, cfg, fsys, func(ctx context.Context, cfg Configurator, fsys fs.FS) (*ClickHouse, error) {
+		return NewWithDB(ctx, db, cfg, fsys)
+	})
+}
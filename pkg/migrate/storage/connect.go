@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrUnableToConnect = errors.New("unable to connect")
+	ErrPoolTooSmall    = errors.New("connection pool is too small")
+)
+
+const (
+	// MinPoolConns is the number of connections a migration holds at once: the dedicated connection
+	// of the migrate driver and one more for the history writes.
+	MinPoolConns = 2
+	// MinLockPoolConns adds the dedicated connection which holds the session level lock, see Locker.
+	MinLockPoolConns = MinPoolConns + 1
+)
+
+var (
+	// ConnectRetryInterval is the delay before the first retry, it's doubled after each attempt.
+	ConnectRetryInterval = 250 * time.Millisecond
+	// ConnectRetryMaxInterval limits the delay between attempts.
+	ConnectRetryMaxInterval = 5 * time.Second
+)
+
+// Connect calls ping until it succeeds or the retries are exhausted (the first attempt is not a retry),
+// the delay between attempts grows exponentially. The whole process is limited by timeout (unlimited when zero).
+func Connect(ctx context.Context, retries int, timeout time.Duration, ping func(ctx context.Context) error) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	started := time.Now()
+	interval := ConnectRetryInterval
+
+	for attempt := 1; ; attempt++ {
+		err := ping(ctx)
+		if err == nil {
+			return nil
+		}
+		if attempt > retries {
+			return fmt.Errorf("%w: %d attempt(s) in %s: %w", ErrUnableToConnect, attempt, time.Since(started).Round(time.Millisecond), err)
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w: %d attempt(s) in %s: %w", ErrUnableToConnect, attempt, time.Since(started).Round(time.Millisecond), err)
+		case <-timer.C:
+		}
+
+		if interval *= 2; interval > ConnectRetryMaxInterval {
+			interval = ConnectRetryMaxInterval
+		}
+	}
+}
+
+// CheckPool fails fast when the pool is limited (see sql.DB.SetMaxOpenConns) to fewer than min connections,
+// otherwise the migration would wait for a connection held by itself forever.
+func CheckPool(db *sql.DB, min int) error {
+	if limit := db.Stats().MaxOpenConnections; limit > 0 && limit < min {
+		return fmt.Errorf("%w: %d max open connection(s), at least %d required", ErrPoolTooSmall, limit, min)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestConnect(t *testing.T) {
+	defer func(interval time.Duration) { ConnectRetryInterval = interval }(ConnectRetryInterval)
+	ConnectRetryInterval = time.Millisecond
+
+	attempts := 0
+	ping := func(_ context.Context) error {
+		if attempts++; attempts < 3 {
+			return errors.New("connection refused")
+		}
+		return nil
+	}
+
+	if err := Connect(context.Background(), 1, 0, ping); !errors.Is(err, ErrUnableToConnect) {
+		t.Fatalf("expected %v, got %v", ErrUnableToConnect, err)
+	}
+
+	attempts = 0
+	if err := Connect(context.Background(), 5, 0, ping); err != nil || attempts != 3 {
+		t.Fatalf("unexpected result: attempts=%d, err=%v", attempts, err)
+	}
+
+	attempts = 0
+	if err := Connect(context.Background(), 100, 10*time.Millisecond, func(_ context.Context) error {
+		attempts++
+		return errors.New("connection refused")
+	}); !errors.Is(err, ErrUnableToConnect) {
+		t.Fatalf("expected %v, got %v", ErrUnableToConnect, err)
+	}
+}
+
+// connector is never connected, CheckPool only reads the pool limits.
+type connector struct{}
+
+func (connector) Connect(context.Context) (driver.Conn, error) {
+	return nil, errors.New("not connected")
+}
+func (connector) Driver() driver.Driver { return nil }
+
+func TestCheckPool(t *testing.T) {
+	tests := []struct {
+		limit int
+		min   int
+		err   error
+	}{
+		{limit: 0, min: MinLockPoolConns},
+		{limit: 1, min: MinPoolConns, err: ErrPoolTooSmall},
+		{limit: 2, min: MinPoolConns},
+		{limit: 2, min: MinLockPoolConns, err: ErrPoolTooSmall},
+		{limit: 3, min: MinLockPoolConns},
+	}
+
+	for _, tt := range tests {
+		db := sql.OpenDB(connector{})
+		db.SetMaxOpenConns(tt.limit)
+
+		if err := CheckPool(db, tt.min); !errors.Is(err, tt.err) {
+			t.Fatalf("limit %d, min %d: expected %v, got %v", tt.limit, tt.min, tt.err, err)
+		}
+		_ = db.Close()
+	}
+}
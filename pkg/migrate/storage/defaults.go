@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// SetDefaults sets each field of the config struct to the value of its `default` tag (the same one used by envconfig),
+// drivers use it to make DefaultConfig, so the programmatic and env configs share the defaults.
+func SetDefaults[C any](cfg *C) *C {
+	setDefaults(reflect.ValueOf(cfg).Elem(), false)
+	return cfg
+}
+
+// WithDefaults returns a copy of the config which zero fields are set to the value of their `default` tag.
+// Boolean fields are left as is since false can't be told apart from the omitted value.
+func WithDefaults[C any](cfg *C) *C {
+	c := *cfg
+	setDefaults(reflect.ValueOf(&c).Elem(), true)
+	return &c
+}
+
+// setDefaults panics on a malformed tag or an unsupported field kind, both are bugs of the driver config.
+func setDefaults(v reflect.Value, onlyZero bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		def, ok := t.Field(i).Tag.Lookup("default")
+		field := v.Field(i)
+		if !ok || !field.CanSet() || (onlyZero && (!field.IsZero() || field.Kind() == reflect.Bool)) {
+			continue
+		}
+
+		if err := setDefault(field, def); err != nil {
+			panic("storage: invalid default of " + t.Name() + "." + t.Field(i).Name + ": " + err.Error())
+		}
+	}
+}
+
+func setDefault(field reflect.Value, def string) error {
+	var err error
+	switch {
+	case field.Type() == reflect.TypeOf(time.Duration(0)):
+		var d time.Duration
+		if d, err = time.ParseDuration(def); err == nil {
+			field.SetInt(int64(d))
+		}
+	case field.Kind() == reflect.String:
+		field.SetString(def)
+	case field.Kind() == reflect.Bool:
+		var b bool
+		if b, err = strconv.ParseBool(def); err == nil {
+			field.SetBool(b)
+		}
+	case field.CanInt():
+		var n int64
+		if n, err = strconv.ParseInt(def, 10, field.Type().Bits()); err == nil {
+			field.SetInt(n)
+		}
+	case field.CanUint():
+		var n uint64
+		if n, err = strconv.ParseUint(def, 10, field.Type().Bits()); err == nil {
+			field.SetUint(n)
+		}
+	default:
+		err = errors.New("unsupported kind " + field.Kind().String())
+	}
+	return err
+}
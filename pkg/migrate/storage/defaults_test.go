@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"maps"
+	"testing"
+	"time"
+)
+
+type defaultsConfig struct {
+	Dir     string        `default:"migrations"`
+	Table   string        `default:"migration_versions"`
+	TTL     time.Duration `default:"1m"`
+	Retries int           `default:"3"`
+	Multi   bool          `default:"true"`
+	Host    string
+}
+
+type envConfig struct {
+	Host    string            `envconfig:"TEST_DB_HOST"`
+	Dir     string            `envconfig:"TEST_DB_DIR" default:"migrations"`
+	Retries int               `envconfig:"TEST_DB_RETRIES" default:"3"`
+	Params  map[string]string `envconfig:"TEST_DB_PARAMS"`
+}
+
+func TestLoadEnv(t *testing.T) {
+	t.Setenv("TEST_DB_HOST", "default.local")
+	t.Setenv("TEST_DB_DIR", "db")
+	t.Setenv("TEST_DB_PARAMS", "sslmode:disable")
+	t.Setenv("BILLING_TEST_DB_RETRIES", "5")
+
+	cfg, err := LoadEnv[envConfig]("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Host != "default.local" || cfg.Dir != "db" || cfg.Retries != 3 ||
+		!maps.Equal(cfg.Params, map[string]string{"sslmode": "disable"}) {
+		t.Fatalf("unexpected default instance config: %+v", cfg)
+	}
+
+	// the unprefixed variables of the default instance are not taken
+	if cfg, err = LoadEnv[envConfig]("BILLING"); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Host != "" || cfg.Dir != "migrations" || cfg.Retries != 5 || cfg.Params != nil {
+		t.Fatalf("unexpected named instance config: %+v", cfg)
+	}
+}
+
+func TestDefaults(t *testing.T) {
+	cfg := SetDefaults(new(defaultsConfig))
+	if *cfg != (defaultsConfig{Dir: "migrations", Table: "migration_versions", TTL: time.Minute, Retries: 3, Multi: true}) {
+		t.Fatalf("unexpected defaults: %+v", cfg)
+	}
+
+	given := &defaultsConfig{Table: "versions", Host: "localhost"}
+	cfg = WithDefaults(given)
+	if *cfg != (defaultsConfig{Dir: "migrations", Table: "versions", TTL: time.Minute, Retries: 3, Host: "localhost"}) {
+		t.Fatalf("unexpected config: %+v", cfg)
+	}
+	if given.Dir != "" {
+		t.Fatalf("the given config must be left as is: %+v", given)
+	}
+}
@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+)
+
+// Driver describes how the storage S of a driver is made from its config C, drivers use it to provide
+// the registered constructor and the programmatic options (see postgres.WithConfig and so on) in the same way.
+type Driver[C any, S Storager] struct {
+	// Name of the driver, the default instance is named after it.
+	Name string
+	// Load loads the instance config from env by the prefix (see Instance.EnvPrefix).
+	Load func(prefix string) (C, error)
+	// Enabled reports whether migrations of the env configured instance are enabled,
+	// the explicitly configured instances (see With) are always enabled.
+	Enabled func(cfg C) bool
+	// Defaults fills the omitted fields of the explicitly given config (see WithDefaults).
+	Defaults func(cfg C) C
+	// New makes the storage which opens its own connection.
+	New func(ctx context.Context, cfg C, fsys fs.FS) (S, error)
+	// NewNamed replaces New (if set) when the connection depends on the instance name,
+	// e.g. the TLS config registered in the MySQL driver.
+	NewNamed func(ctx context.Context, name string, cfg C, fsys fs.FS) (S, error)
+	// Rename names the made storage after its instance.
+	Rename func(s S, name string)
+	// ErrLoad and ErrCreate wrap the errors of Load and New.
+	ErrLoad   error
+	ErrCreate error
+}
+
+// Make loads the instance config from env and creates the storage, it's registered as the Constructor.
+func (d Driver[C, S]) Make(ctx context.Context, instance Instance) (Storager, error) {
+	cfg, err := d.Load(instance.EnvPrefix())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", d.ErrLoad, err)
+	}
+
+	if !d.Enabled(cfg) {
+		return nil, ErrMigrationsIsNotEnabled
+	}
+	return d.build(ctx, instance, cfg, d.open(instance.Name))
+}
+
+// WithConfig adds the named instance of the storage configured by the given config.
+func (d Driver[C, S]) WithConfig(name string, cfg C, fsys fs.FS) Option {
+	return d.With(name, cfg, fsys, d.open(name))
+}
+
+// With adds the named instance of the storage made by newFn from the given config
+// (e.g. on top of the existing connection of the application). The omitted fields of the config are filled
+// by the env defaults and the instance is enabled regardless of its config.
+func (d Driver[C, S]) With(name string, cfg C, fsys fs.FS, newFn func(ctx context.Context, cfg C, fsys fs.FS) (S, error)) Option {
+	if d.Defaults != nil {
+		cfg = d.Defaults(cfg)
+	}
+
+	instance := Instance{Name: name, Driver: d.Name, FS: fsys}
+	return WithStorage(instance, func(ctx context.Context, instance Instance) (Storager, error) {
+		return d.build(ctx, instance, cfg, newFn)
+	})
+}
+
+// open returns the constructor of the storage which opens its own connection for the named instance.
+func (d Driver[C, S]) open(name string) func(ctx context.Context, cfg C, fsys fs.FS) (S, error) {
+	if d.NewNamed == nil {
+		return d.New
+	}
+	return func(ctx context.Context, cfg C, fsys fs.FS) (S, error) {
+		return d.NewNamed(ctx, name, cfg, fsys)
+	}
+}
+
+func (d Driver[C, S]) build(
+	ctx context.Context,
+	instance Instance,
+	cfg C,
+	newFn func(ctx context.Context, cfg C, fsys fs.FS) (S, error),
+) (Storager, error) {
+	if instance.FS == nil {
+		return nil, ErrFSWasOmitted
+	}
+
+	s, err := newFn(ctx, cfg, instance.FS)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", d.ErrCreate, err)
+	}
+	d.Rename(s, instance.Name)
+
+	return s, nil
+}
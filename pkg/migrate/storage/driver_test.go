@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func TestDriver(t *testing.T) {
+	errLoad, errCreate := errors.New("load"), errors.New("create")
+
+	d := Driver[bool, *TestStorage]{
+		Name:    "test",
+		Load:    func(prefix string) (bool, error) { return prefix == "", nil },
+		Enabled: func(enabled bool) bool { return enabled },
+		New: func(_ context.Context, _ bool, _ fs.FS) (*TestStorage, error) {
+			return &TestStorage{name: "test"}, nil
+		},
+		Rename:    func(s *TestStorage, name string) { s.name = name },
+		ErrLoad:   errLoad,
+		ErrCreate: errCreate,
+	}
+
+	s, err := d.Make(context.Background(), Instance{Name: "test", Driver: "test", FS: fstest.MapFS{}})
+	if err != nil || s.Name() != "test" {
+		t.Fatalf("unexpected storage: %v, %v", s, err)
+	}
+
+	// the prefixed instance is disabled by the Load stub
+	if _, err = d.Make(context.Background(), Instance{Name: "billing", Driver: "test", FS: fstest.MapFS{}}); !errors.Is(err, ErrMigrationsIsNotEnabled) {
+		t.Fatalf("expected ErrMigrationsIsNotEnabled, got: %v", err)
+	}
+	if _, err = d.Make(context.Background(), Instance{Name: "test", Driver: "test"}); !errors.Is(err, ErrFSWasOmitted) {
+		t.Fatalf("expected ErrFSWasOmitted, got: %v", err)
+	}
+
+	failing := func(_ context.Context, _ bool, _ fs.FS) (*TestStorage, error) { return nil, errors.New("dial") }
+	f := NewFactory(nil, WithoutEnv(),
+		d.WithConfig("billing", true, fstest.MapFS{}),
+		d.With("users", true, fstest.MapFS{}, failing),
+	)
+	if _, err = f.instances[0].constructor(context.Background(), f.instances[0]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = f.instances[1].constructor(context.Background(), f.instances[1]); !errors.Is(err, errCreate) {
+		t.Fatalf("expected the create error, got: %v", err)
+	}
+}
+
+func TestDriver_ExplicitConfig(t *testing.T) {
+	var got string
+	d := Driver[string, *TestStorage]{
+		Name:     "test",
+		Enabled:  func(string) bool { return false },
+		Defaults: func(cfg string) string { return cfg + ":defaults" },
+		New: func(_ context.Context, cfg string, _ fs.FS) (*TestStorage, error) {
+			got = cfg
+			return &TestStorage{}, nil
+		},
+		Rename: func(s *TestStorage, name string) { s.name = name },
+	}
+
+	f := NewFactory(nil, WithoutEnv(), d.WithConfig("billing", "cfg", fstest.MapFS{}))
+	s, err := f.instances[0].constructor(context.Background(), f.instances[0])
+	if err != nil {
+		t.Fatalf("expected the explicitly given storage to be enabled: %v", err)
+	}
+	if s.Name() != "billing" || got != "cfg:defaults" {
+		t.Fatalf("unexpected storage: %s, config: %s", s.Name(), got)
+	}
+}
+
+func TestDriver_NewNamed(t *testing.T) {
+	var names []string
+	d := Driver[bool, *TestStorage]{
+		Name:    "test",
+		Load:    func(string) (bool, error) { return true, nil },
+		Enabled: func(enabled bool) bool { return enabled },
+		NewNamed: func(_ context.Context, name string, _ bool, _ fs.FS) (*TestStorage, error) {
+			names = append(names, name)
+			return &TestStorage{}, nil
+		},
+		Rename: func(s *TestStorage, name string) { s.name = name },
+	}
+
+	if _, err := d.Make(context.Background(), Instance{Name: "billing", Driver: "test", FS: fstest.MapFS{}}); err != nil {
+		t.Fatal(err)
+	}
+	f := NewFactory(nil, WithoutEnv(), d.WithConfig("users", true, fstest.MapFS{}))
+	if _, err := f.instances[0].constructor(context.Background(), f.instances[0]); err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 2 || names[0] != "billing" || names[1] != "users" {
+		t.Fatalf("unexpected instance names: %v", names)
+	}
+}
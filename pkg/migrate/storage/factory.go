@@ -2,170 +2,141 @@ package storage
 
 import (
 	"context"
-	"embed"
 	"errors"
+	"fmt"
 	"github.com/Borislavv/go-logger/pkg/logger"
-	"github.com/Borislavv/go-migrate/pkg/migrate/storage/mongo"
-	"github.com/Borislavv/go-migrate/pkg/migrate/storage/mysql"
-	"github.com/Borislavv/go-migrate/pkg/migrate/storage/postgres"
+	"io/fs"
 )
 
 var (
-	ErrFailedLoadMongoDBConfig  = errors.New("failed to load MongoDB config")
-	ErrFailedLoadMySQLConfig    = errors.New("failed to load MySQL config")
-	ErrFailedLoadPostgresConfig = errors.New("failed to load PostgreSQL config")
+	ErrMigrationsIsNotEnabled = errors.New("migrations is not enabled")
+	ErrFSWasOmitted           = errors.New("unable to migrate, target filesystem was omitted")
+	ErrUnableToGetMigrator    = errors.New("unable to get migrator")
+	ErrUnknownDriver          = errors.New("unknown storage driver")
+	ErrDuplicateInstance      = errors.New("duplicate storage instance name")
+	ErrInstanceNameIsEmpty    = errors.New("storage instance name is empty")
 )
 
-var (
-	ErrFailedCreateInstanceMongoDB  = errors.New("failed to create MongoDB instance")
-	ErrFailedCreateInstanceMySQL    = errors.New("failed to create MySQL instance")
-	ErrFailedCreateInstancePostgres = errors.New("failed to create PostgreSQL instance")
-)
-
-var (
-	ErrUnableToGetMongoDBMigrator  = errors.New("unable to get MongoDB migrator")
-	ErrUnableToGetMySQLMigrator    = errors.New("unable to get MySQL migrator")
-	ErrUnableToGetPostgresMigrator = errors.New("unable to get PostgreSQL migrator")
-)
-
-var (
-	ErrMongoDBMigrationsIsNotEnabled  = errors.New("MongoDB migrations is not enabled")
-	ErrMySQLMigrationsIsNotEnabled    = errors.New("MySQL migrations is not enabled")
-	ErrPostgresMigrationsIsNotEnabled = errors.New("PostgreSQL migrations is not enabled")
-)
-
-var (
-	ErrMongoDBFSWasOmitted    = errors.New("unable to migrate MongoDB, target filesystem was omitted")
-	ErrMySQLFSWasOmitted      = errors.New("unable to migrate MySQL, target filesystem was omitted")
-	ErrPostgreSQLFSWasOmitted = errors.New("unable to migrate PostgreSQL, target filesystem was omitted")
-)
-
-type Storage int
+// Storage is the name of a registered storage.
+type Storage = string
 
 const (
-	MongoDB Storage = iota
-	MySQL
-	PostgreSQL
+	ClickHouse Storage = "clickhouse"
+	MongoDB    Storage = "mongodb"
+	MySQL      Storage = "mysql"
+	PostgreSQL Storage = "postgres"
+	Redis      Storage = "redis"
+	SQLite     Storage = "sqlite"
 )
 
-type Filesystems map[Storage]embed.FS
+// Filesystems maps each storage to the filesystem holding its migrations directory.
+type Filesystems map[Storage]fs.FS
 
 type Factory struct {
 	logger      logger.Logger
 	filesystems Filesystems
+	instances   []Instance
+	withoutEnv  bool
+	// only are the selected instance names, see Only
+	only map[string]struct{}
 }
 
-func NewFactory(logger logger.Logger, filesystems Filesystems) *Factory {
-	return &Factory{logger: logger, filesystems: filesystems}
+// NewFactory creates a factory of the default storages (one per registered driver, configured by the unprefixed env)
+// and of the additional instances given by options, e.g.:
+//
+//	storage.NewFactory(lgr, storage.Filesystems{...}, storage.Instance{...}, postgres.WithConfig(cfg, fsys))
+func NewFactory(logger logger.Logger, opts ...Option) *Factory {
+	f := &Factory{logger: logger, filesystems: make(Filesystems)}
+	for _, opt := range opts {
+		opt.apply(f)
+	}
+	return f
 }
 
+// Make iterates over registered storages and named instances and returns the enabled ones,
+// a filesystem of the driver which is not registered (not imported) is reported as a warning.
 func (f *Factory) Make(ctx context.Context) ([]Storager, error) {
-	storages := make([]Storager, 0, 3)
-
-	if s, err := f.getMongo(ctx); err != nil {
-		if !errors.Is(err, ErrMongoDBMigrationsIsNotEnabled) {
-			return nil, f.logger.Error(ctx, ErrUnableToGetMongoDBMigrator, logger.Fields{
-				"err": err.Error(),
-			})
-		}
-	} else {
-		storages = append(storages, s)
+	instances, err := f.all()
+	if err != nil {
+		return nil, f.logger.Error(ctx, ErrUnableToGetMigrator, logger.Fields{"err": err.Error()})
 	}
 
-	if s, err := f.getMySQL(ctx); err != nil {
-		if !errors.Is(err, ErrMySQLMigrationsIsNotEnabled) {
-			return nil, f.logger.Error(ctx, ErrUnableToGetMySQLMigrator, logger.Fields{
-				"err": err.Error(),
-			})
+	for name := range f.filesystems {
+		if _, ok := constructor(name); !ok {
+			f.logger.WarningMsg(ctx, "migrations: [storage: "+name+"]: driver is not registered (its package must be imported), the filesystem is ignored", nil)
 		}
-	} else {
-		storages = append(storages, s)
 	}
 
-	if s, err := f.getPostgres(ctx); err != nil {
-		if !errors.Is(err, ErrPostgresMigrationsIsNotEnabled) {
-			return nil, f.logger.Error(ctx, ErrUnableToGetPostgresMigrator, logger.Fields{
-				"err": err.Error(),
+	storages := make([]Storager, 0, len(instances))
+	for _, instance := range instances {
+		s, err := f.get(ctx, instance)
+		if err != nil {
+			if errors.Is(err, ErrMigrationsIsNotEnabled) {
+				continue
+			}
+			return nil, f.logger.Error(ctx, ErrUnableToGetMigrator, logger.Fields{
+				"err":     err.Error(),
+				"storage": instance.Name,
 			})
 		}
-	} else {
 		storages = append(storages, s)
 	}
 
 	return storages, nil
 }
 
-func (f *Factory) getMongo(ctx context.Context) (Storager, error) {
-	cfg, err := mongo.Load()
-	if err != nil {
-		return nil, f.logger.Error(ctx, ErrFailedLoadMongoDBConfig, logger.Fields{
-			"err": err.Error(),
-		})
-	}
-	if cfg.MongoMigrationsEnabled {
-		fs, ok := f.filesystems[MongoDB]
-		if !ok {
-			return nil, f.logger.Error(ctx, ErrMongoDBFSWasOmitted, nil)
+// all returns the default instances of registered drivers followed by the named ones (the selected ones if any),
+// an explicit instance named after its driver replaces the default one.
+func (f *Factory) all() ([]Instance, error) {
+	explicit := make(map[string]struct{}, len(f.instances))
+	for _, instance := range f.instances {
+		if instance.Name == "" {
+			return nil, fmt.Errorf("%w: driver %s", ErrInstanceNameIsEmpty, instance.Driver)
 		}
-
-		m, err := mongo.New(ctx, cfg, fs)
-		if err != nil {
-			return nil, f.logger.Error(ctx, ErrFailedCreateInstanceMongoDB, logger.Fields{
-				"err": err.Error(),
-			})
+		if _, dup := explicit[instance.Name]; dup {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicateInstance, instance.Name)
 		}
-		return m, nil
+		explicit[instance.Name] = struct{}{}
 	}
 
-	return nil, ErrMongoDBMigrationsIsNotEnabled
-}
+	var drivers []string
+	if !f.withoutEnv {
+		drivers = Drivers()
+	}
 
-func (f *Factory) getMySQL(ctx context.Context) (Storager, error) {
-	cfg, err := mysql.Load()
-	if err != nil {
-		return nil, f.logger.Error(ctx, ErrFailedLoadMySQLConfig, logger.Fields{
-			"err": err.Error(),
-		})
+	instances := make([]Instance, 0, len(drivers)+len(f.instances))
+	for _, driver := range drivers {
+		if _, replaced := explicit[driver]; !replaced {
+			instances = append(instances, Instance{Name: driver, Driver: driver, FS: f.filesystems[driver]})
+		}
 	}
-	if cfg.MySQLMigrationsEnabled {
-		fs, ok := f.filesystems[MySQL]
-		if !ok {
-			return nil, f.logger.Error(ctx, ErrMySQLFSWasOmitted, nil)
+	for _, instance := range f.instances {
+		if _, isDriver := constructor(instance.Name); isDriver && instance.Name != instance.Driver {
+			return nil, fmt.Errorf("%w: %s is a name of another driver", ErrDuplicateInstance, instance.Name)
 		}
+		instances = append(instances, instance)
+	}
 
-		m, err := mysql.New(ctx, cfg, fs)
-		if err != nil {
-			return nil, f.logger.Error(ctx, ErrFailedCreateInstanceMySQL, logger.Fields{
-				"err": err.Error(),
-			})
+	if len(f.only) == 0 {
+		return instances, nil
+	}
+	selected := instances[:0]
+	for _, instance := range instances {
+		if _, ok := f.only[instance.Name]; ok {
+			selected = append(selected, instance)
 		}
-		return m, nil
 	}
-
-	return nil, ErrMySQLMigrationsIsNotEnabled
+	return selected, nil
 }
 
-func (f *Factory) getPostgres(ctx context.Context) (Storager, error) {
-	cfg, err := postgres.Load()
-	if err != nil {
-		return nil, f.logger.Error(ctx, ErrFailedLoadPostgresConfig, logger.Fields{
-			"err": err.Error(),
-		})
+func (f *Factory) get(ctx context.Context, instance Instance) (Storager, error) {
+	if instance.constructor != nil {
+		return instance.constructor(ctx, instance)
 	}
-	if cfg.PostgresMigrationsEnabled {
-		fs, ok := f.filesystems[PostgreSQL]
-		if !ok {
-			return nil, f.logger.Error(ctx, ErrPostgreSQLFSWasOmitted, nil)
-		}
 
-		m, err := postgres.New(ctx, cfg, fs)
-		if err != nil {
-			return nil, f.logger.Error(ctx, ErrFailedCreateInstancePostgres, logger.Fields{
-				"err": err.Error(),
-			})
-		}
-		return m, nil
+	c, ok := constructor(instance.Driver)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownDriver, instance.Driver)
 	}
-
-	return nil, ErrPostgresMigrationsIsNotEnabled
+	return c(ctx, instance)
 }
@@ -0,0 +1,186 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"github.com/Borislavv/go-logger/pkg/logger"
+	loggerenum "github.com/Borislavv/go-logger/pkg/logger/enum"
+	"github.com/Borislavv/go-migrate/pkg/migrate/source"
+	"testing"
+	"testing/fstest"
+)
+
+type TestStorage struct {
+	name string
+}
+
+func (s *TestStorage) Name() string {
+	return s.name
+}
+func (s *TestStorage) Up(_ context.Context) error {
+	return nil
+}
+func (s *TestStorage) Down(_ context.Context) error {
+	return nil
+}
+func (s *TestStorage) Migrate(_ context.Context, _ uint) error {
+	return nil
+}
+func (s *TestStorage) Steps(_ context.Context, _ int) error {
+	return nil
+}
+func (s *TestStorage) Force(_ context.Context, _ int) error {
+	return nil
+}
+func (s *TestStorage) Version(_ context.Context) (uint, bool, error) {
+	return 0, false, nil
+}
+func (s *TestStorage) Migrations() ([]source.Migration, error) {
+	return nil, nil
+}
+func (s *TestStorage) ReadMigration(_ string) ([]byte, error) {
+	return nil, nil
+}
+
+func TestFactory_Make(t *testing.T) {
+	Register("test-enabled", func(_ context.Context, instance Instance) (Storager, error) {
+		if instance.FS == nil {
+			return nil, ErrFSWasOmitted
+		}
+		return &TestStorage{name: instance.Name}, nil
+	})
+	Register("test-disabled", func(_ context.Context, _ Instance) (Storager, error) {
+		return nil, ErrMigrationsIsNotEnabled
+	})
+
+	out, cancel, err := logger.NewOutput(loggerenum.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel()
+
+	lgr, cancel, err := logger.NewLogrus(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel()
+
+	storages, err := NewFactory(lgr, Filesystems{"test-enabled": fstest.MapFS{}}).Make(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(storages) != 1 || storages[0].Name() != "test-enabled" {
+		t.Fatalf("unexpected storages: %v", storages)
+	}
+
+	if _, err = NewFactory(lgr, Filesystems{}).Make(context.Background()); !errors.Is(err, ErrUnableToGetMigrator) {
+		t.Fatalf("expected %v, got %v", ErrUnableToGetMigrator, err)
+	}
+
+	storages, err = NewFactory(lgr, Filesystems{"test-enabled": fstest.MapFS{}},
+		Instance{Name: "billing", Driver: "test-enabled", FS: fstest.MapFS{}},
+		Instance{Name: "users", Driver: "test-enabled", FS: fstest.MapFS{}},
+	).Make(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(storages) != 3 || storages[1].Name() != "billing" || storages[2].Name() != "users" {
+		t.Fatalf("unexpected storages: %v", storages)
+	}
+
+	_, err = NewFactory(lgr,
+		Instance{Name: "billing", Driver: "test-enabled", FS: fstest.MapFS{}},
+		Instance{Name: "billing", Driver: "test-enabled", FS: fstest.MapFS{}},
+	).Make(context.Background())
+	if !errors.Is(err, ErrUnableToGetMigrator) {
+		t.Fatalf("expected %v, got %v", ErrUnableToGetMigrator, err)
+	}
+}
+
+func TestFactory_WithStorage(t *testing.T) {
+	Register("test-env", func(_ context.Context, instance Instance) (Storager, error) {
+		return &TestStorage{name: "env:" + instance.Name}, nil
+	})
+	programmatic := func(_ context.Context, instance Instance) (Storager, error) {
+		return &TestStorage{name: "programmatic:" + instance.Name}, nil
+	}
+
+	out, cancel, err := logger.NewOutput(loggerenum.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel()
+
+	lgr, cancel, err := logger.NewLogrus(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel()
+
+	storages, err := NewFactory(lgr,
+		Filesystems{"test-enabled": fstest.MapFS{}},
+		WithStorage(Instance{Name: "test-env", Driver: "test-env", FS: fstest.MapFS{}}, programmatic),
+	).Make(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range storages {
+		if s.Name() == "env:test-env" {
+			t.Fatal("the default instance must be replaced by the programmatic one")
+		}
+	}
+
+	storages, err = NewFactory(lgr,
+		WithoutEnv(),
+		WithStorage(Instance{Name: "billing", Driver: "test-env", FS: fstest.MapFS{}}, programmatic),
+	).Make(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(storages) != 1 || storages[0].Name() != "programmatic:billing" {
+		t.Fatalf("unexpected storages: %v", storages)
+	}
+}
+
+func TestInstance_EnvPrefix(t *testing.T) {
+	if prefix := (Instance{Name: "postgres", Driver: "postgres"}).EnvPrefix(); prefix != "" {
+		t.Fatalf("expected empty prefix, got %q", prefix)
+	}
+	if prefix := (Instance{Name: "billing-eu", Driver: "postgres"}).EnvPrefix(); prefix != "BILLING_EU" {
+		t.Fatalf("expected BILLING_EU, got %q", prefix)
+	}
+}
+
+func TestFactory_Only(t *testing.T) {
+	out, cancel, err := logger.NewOutput(loggerenum.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel()
+
+	lgr, cancel, err := logger.NewLogrus(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel()
+
+	unreachable := func(_ context.Context, _ Instance) (Storager, error) {
+		t.Fatal("the storage which is not selected must not be made")
+		return nil, nil
+	}
+	reachable := func(_ context.Context, instance Instance) (Storager, error) {
+		return &TestStorage{name: instance.Name}, nil
+	}
+
+	storages, err := NewFactory(lgr,
+		WithStorage(Instance{Name: "billing", Driver: "test-env", FS: fstest.MapFS{}}, unreachable),
+		WithStorage(Instance{Name: "users", Driver: "test-env", FS: fstest.MapFS{}}, reachable),
+		Only("users"),
+	).Make(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(storages) != 1 || storages[0].Name() != "users" {
+		t.Fatalf("unexpected storages: %v", storages)
+	}
+}
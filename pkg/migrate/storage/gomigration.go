@@ -0,0 +1,321 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/Borislavv/go-migrate/pkg/migrate/source"
+	"github.com/golang-migrate/migrate/v4/database"
+	migratesource "github.com/golang-migrate/migrate/v4/source"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var ErrGoMigrationConflict = errors.New("go migration conflicts with the migration file of the same version")
+
+const (
+	// GoMigrationName is the name of the Go migrations in the listings and the history.
+	GoMigrationName = "go"
+	// goPrefix marks the paths of the Go migrations, the path is also used as the migration content,
+	// so the Go migrations pass through the migrate run loop as regular ones (see GoSource and GoDriver).
+	// The content is never used to tell them apart, they are resolved by version among the registered ones.
+	goPrefix = "go:"
+)
+
+// GoFunc is the Go code migration, db is the handle of the storage which executes it: *sql.Tx for PostgreSQL,
+// MySQL and SQLite (committed before the version is marked as clean), *sql.DB for ClickHouse,
+// *mongo.Database for MongoDB and redis.UniversalClient for Redis.
+type GoFunc func(ctx context.Context, db any) error
+
+// GoExecutor executes the Go migration against the storage handle.
+type GoExecutor func(ctx context.Context, fn GoFunc) error
+
+type goMigration struct {
+	up, down GoFunc
+}
+
+var (
+	goMu         sync.RWMutex
+	goMigrations = make(map[string]map[uint]goMigration)
+)
+
+// RegisterGo adds the Go migration of the version to the storage (a driver or an instance name), it's interleaved
+// with the migration files in version order and tracked in the same version table. The down func may be nil.
+// It panics if the version is zero, the up func is nil or the version is already registered for the storage.
+func RegisterGo(storage string, version uint, up, down GoFunc) {
+	goMu.Lock()
+	defer goMu.Unlock()
+
+	if version == 0 {
+		panic("storage: RegisterGo version is zero for " + storage)
+	}
+	if up == nil {
+		panic("storage: RegisterGo up func is nil for " + storage + "@" + strconv.FormatUint(uint64(version), 10))
+	}
+	if _, dup := goMigrations[storage][version]; dup {
+		panic("storage: RegisterGo called twice for " + storage + "@" + strconv.FormatUint(uint64(version), 10))
+	}
+
+	if goMigrations[storage] == nil {
+		goMigrations[storage] = make(map[uint]goMigration)
+	}
+	goMigrations[storage][version] = goMigration{up: up, down: down}
+}
+
+// WithGoMigrations merges the Go migrations registered for the storage into the migration files ordered by version.
+func WithGoMigrations(storage string, migrations []source.Migration) ([]source.Migration, error) {
+	goMu.RLock()
+	defer goMu.RUnlock()
+
+	if len(goMigrations[storage]) == 0 {
+		return migrations, nil
+	}
+
+	for _, migration := range migrations {
+		if _, ok := goMigrations[storage][migration.Version]; ok {
+			return nil, fmt.Errorf("%w: %s@%d", ErrGoMigrationConflict, storage, migration.Version)
+		}
+	}
+
+	for version, registered := range goMigrations[storage] {
+		migration := source.Migration{Version: version, Name: GoMigrationName, Up: goPath(version, migratesource.Up)}
+		if registered.down != nil {
+			migration.Down = goPath(version, migratesource.Down)
+		}
+		migrations = append(migrations, migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// IsGoMigration reports whether the migration of the version is the Go one registered for the storage.
+// The content of a Go migration is its path, so it's excluded from the checksum verification.
+func IsGoMigration(storage string, version uint) bool {
+	goMu.RLock()
+	defer goMu.RUnlock()
+
+	_, ok := goMigrations[storage][version]
+	return ok
+}
+
+// ReadGoMigration returns the content of the Go migration of the storage by its path,
+// false is returned for the other paths.
+func ReadGoMigration(storage, path string) ([]byte, bool) {
+	raw, _, _ := strings.Cut(strings.TrimPrefix(path, goPrefix), ".")
+	version, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil || !IsGoMigration(storage, uint(version)) {
+		return nil, false
+	}
+	if path != goPath(uint(version), migratesource.Up) && path != goPath(uint(version), migratesource.Down) {
+		return nil, false
+	}
+	return []byte(path), true
+}
+
+// GoSource wraps the source of the migration files so it also serves the Go ones of the storage,
+// migrations must be the merged ones (see WithGoMigrations).
+func GoSource(src migratesource.Driver, storage string, migrations []source.Migration) migratesource.Driver {
+	s := &goSource{Driver: src, storage: storage, migrations: make(map[uint]source.Migration, len(migrations))}
+	for _, migration := range migrations {
+		s.versions = append(s.versions, migration.Version)
+		s.migrations[migration.Version] = migration
+	}
+	return s
+}
+
+type goSource struct {
+	migratesource.Driver
+	storage string
+	// versions are ordered
+	versions   []uint
+	migrations map[uint]source.Migration
+}
+
+func (s *goSource) First() (uint, error) {
+	if len(s.versions) == 0 {
+		return 0, &os.PathError{Op: "first", Path: goPrefix, Err: os.ErrNotExist}
+	}
+	return s.versions[0], nil
+}
+
+func (s *goSource) Prev(version uint) (uint, error) {
+	i, ok := s.index(version)
+	if !ok || i == 0 {
+		return 0, &os.PathError{Op: "prev for version " + strconv.FormatUint(uint64(version), 10), Path: goPrefix, Err: os.ErrNotExist}
+	}
+	return s.versions[i-1], nil
+}
+
+func (s *goSource) Next(version uint) (uint, error) {
+	i, ok := s.index(version)
+	if !ok || i == len(s.versions)-1 {
+		return 0, &os.PathError{Op: "next for version " + strconv.FormatUint(uint64(version), 10), Path: goPrefix, Err: os.ErrNotExist}
+	}
+	return s.versions[i+1], nil
+}
+
+// index returns the position of the available version.
+func (s *goSource) index(version uint) (int, bool) {
+	i := sort.Search(len(s.versions), func(i int) bool { return s.versions[i] >= version })
+	return i, i < len(s.versions) && s.versions[i] == version
+}
+
+func (s *goSource) ReadUp(version uint) (io.ReadCloser, string, error) {
+	return s.read(version, migratesource.Up, s.Driver.ReadUp)
+}
+
+func (s *goSource) ReadDown(version uint) (io.ReadCloser, string, error) {
+	return s.read(version, migratesource.Down, s.Driver.ReadDown)
+}
+
+func (s *goSource) read(
+	version uint,
+	direction migratesource.Direction,
+	read func(version uint) (io.ReadCloser, string, error),
+) (io.ReadCloser, string, error) {
+	migration, ok := s.migrations[version]
+	if !ok || !IsGoMigration(s.storage, version) {
+		return read(version)
+	}
+
+	path := migration.Up
+	if direction == migratesource.Down {
+		path = migration.Down
+	}
+	if path == "" {
+		return nil, "", &os.PathError{Op: "read " + string(direction) + " for version " + strconv.FormatUint(uint64(version), 10), Path: goPrefix, Err: os.ErrNotExist}
+	}
+	return io.NopCloser(strings.NewReader(path)), migration.Name, nil
+}
+
+// GoDriver wraps the migrate driver so the Go migrations are executed by the registered funcs of the storage
+// instead of the driver. It relies on the migrate run loop (the target version is marked as dirty before the run)
+// to resolve the executed migration by version, the content is passed to the driver for the other ones.
+func GoDriver(ctx context.Context, d database.Driver, storage string, exec GoExecutor) database.Driver {
+	return &goDriver{Driver: d, ctx: ctx, storage: storage, exec: exec}
+}
+
+type goDriver struct {
+	database.Driver
+	ctx     context.Context
+	storage string
+	exec    GoExecutor
+	// pending is the migration which is being executed, nil between migrations
+	pending *goStep
+}
+
+type goStep struct {
+	version   uint
+	direction migratesource.Direction
+}
+
+func (d *goDriver) SetVersion(version int, dirty bool) error {
+	if !dirty {
+		d.pending = nil
+		return d.Driver.SetVersion(version, dirty)
+	}
+
+	current, _, err := d.Driver.Version()
+	if err != nil {
+		return err
+	}
+
+	d.pending = &goStep{version: uint(version), direction: migratesource.Up}
+	if version <= current {
+		// the target version of a down migration is the previous one
+		d.pending = &goStep{version: uint(current), direction: migratesource.Down}
+	}
+	return d.Driver.SetVersion(version, dirty)
+}
+
+func (d *goDriver) Run(migration io.Reader) error {
+	// the buffered migration body must be drained anyway
+	content, err := io.ReadAll(migration)
+	if err != nil {
+		return err
+	}
+	if d.pending == nil {
+		return d.Driver.Run(bytes.NewReader(content))
+	}
+	return d.run(d.pending.version, d.pending.direction, content)
+}
+
+// run executes the Go migration of the version or passes the content to the driver.
+func (d *goDriver) run(version uint, direction migratesource.Direction, content []byte) error {
+	if !IsGoMigration(d.storage, version) {
+		return d.Driver.Run(bytes.NewReader(content))
+	}
+
+	fn, err := d.lookup(version, direction)
+	if err != nil {
+		return err
+	}
+	return d.exec(d.ctx, fn)
+}
+
+// lookup resolves the registered func of the Go migration.
+func (d *goDriver) lookup(version uint, direction migratesource.Direction) (GoFunc, error) {
+	goMu.RLock()
+	registered, ok := goMigrations[d.storage][version]
+	goMu.RUnlock()
+
+	fn := registered.up
+	if direction == migratesource.Down {
+		fn = registered.down
+	}
+	if !ok || fn == nil {
+		return nil, fmt.Errorf("go migration %q is not registered for %s", goPath(version, direction), d.storage)
+	}
+	return fn, nil
+}
+
+// runMigration executes the content of the migration of the version outside the migrate run loop (see Apply and Repair),
+// the Go migrations are routed to their funcs when d is made by GoDriver.
+func runMigration(d database.Driver, version uint, direction migratesource.Direction, content []byte) error {
+	if g, ok := d.(*goDriver); ok {
+		return g.run(version, direction, content)
+	}
+	return d.Run(bytes.NewReader(content))
+}
+
+// TxBeginner is implemented by *sql.DB and *sql.Conn.
+type TxBeginner interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// TxExecutor executes the Go migrations in a transaction of db, the connection which runs the migration files
+// should be passed (if it's a dedicated one), so a pool limited to a single connection can't deadlock.
+func TxExecutor(db TxBeginner) GoExecutor {
+	return func(ctx context.Context, fn GoFunc) error {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = tx.Rollback() }()
+
+		if err = fn(ctx, tx); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+}
+
+// HandleExecutor executes the Go migrations against the given handle as is.
+func HandleExecutor(db any) GoExecutor {
+	return func(ctx context.Context, fn GoFunc) error {
+		return fn(ctx, db)
+	}
+}
+
+func goPath(version uint, direction migratesource.Direction) string {
+	return goPrefix + strconv.FormatUint(uint64(version), 10) + "." + string(direction)
+}
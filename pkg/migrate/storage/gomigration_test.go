@@ -0,0 +1,155 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"github.com/Borislavv/go-migrate/pkg/migrate/source"
+	"github.com/golang-migrate/migrate/v4/database"
+	migratesource "github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+type RunDriver struct {
+	database.Driver
+	version int
+	runs    []string
+}
+
+func (d *RunDriver) Version() (int, bool, error) {
+	return d.version, false, nil
+}
+func (d *RunDriver) SetVersion(version int, _ bool) error {
+	d.version = version
+	return nil
+}
+func (d *RunDriver) Run(migration io.Reader) error {
+	content, err := io.ReadAll(migration)
+	d.runs = append(d.runs, string(content))
+	return err
+}
+
+func TestGoMigrations(t *testing.T) {
+	noop := func(context.Context, any) error { return nil }
+	RegisterGo("go-test", 2, noop, nil)
+	RegisterGo("go-test", 4, noop, noop)
+
+	fsys := fstest.MapFS{
+		"migrations/1_create_users.up.sql": {Data: []byte("CREATE TABLE users (id INT);")},
+		"migrations/3_add_email.up.sql":    {Data: []byte("ALTER TABLE users ADD email TEXT;")},
+	}
+
+	files, err := source.List(fsys, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	migrations, err := WithGoMigrations("go-test", files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 4 || migrations[1].Up != "go:2.up" || migrations[1].Down != "" || migrations[3].Down != "go:4.down" {
+		t.Fatalf("unexpected migrations: %+v", migrations)
+	}
+
+	if _, err = WithGoMigrations("go-test", append(files, source.Migration{Version: 4, Up: "migrations/4_x.up.sql"})); !errors.Is(err, ErrGoMigrationConflict) {
+		t.Fatalf("expected ErrGoMigrationConflict, got: %v", err)
+	}
+
+	src, err := iofs.New(fsys, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := GoSource(src, "go-test", migrations)
+
+	var versions []uint
+	for v, err := s.First(); ; v, err = s.Next(v) {
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				t.Fatal(err)
+			}
+			break
+		}
+		versions = append(versions, v)
+	}
+	if len(versions) != 4 || versions[0] != 1 || versions[3] != 4 {
+		t.Fatalf("unexpected versions: %v", versions)
+	}
+	if prev, err := s.Prev(3); err != nil || prev != 2 {
+		t.Fatalf("unexpected prev: %d, %v", prev, err)
+	}
+
+	r, _, err := s.ReadUp(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := io.ReadAll(r); string(content) != "go:2.up" {
+		t.Fatalf("unexpected content: %s", content)
+	}
+	if _, _, err = s.ReadDown(2); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected missing down of the go migration, got: %v", err)
+	}
+	if r, _, err = s.ReadUp(3); err != nil {
+		t.Fatal(err)
+	} else if content, _ := io.ReadAll(r); string(content) != "ALTER TABLE users ADD email TEXT;" {
+		t.Fatalf("unexpected content: %s", content)
+	}
+}
+
+func TestGoDriver(t *testing.T) {
+	var calls []string
+	up := func(context.Context, any) error { calls = append(calls, "up"); return nil }
+	down := func(context.Context, any) error { calls = append(calls, "down"); return nil }
+	RegisterGo("go-driver-test", 2, up, down)
+
+	if _, ok := ReadGoMigration("go-driver-test", "go:2.up"); !ok {
+		t.Fatal("expected the registered go migration to be read")
+	}
+	for _, path := range []string{"go:3.up", "go:2.sideways", "migrations/go:2.up"} {
+		if _, ok := ReadGoMigration("go-driver-test", path); ok {
+			t.Fatalf("expected %s not to be read as a go migration", path)
+		}
+	}
+	if _, ok := ReadGoMigration("other", "go:2.up"); ok {
+		t.Fatal("expected the go migration of another storage not to be read")
+	}
+
+	drv := &RunDriver{version: database.NilVersion}
+	d := GoDriver(context.Background(), drv, "go-driver-test", HandleExecutor(nil))
+
+	// a migration file which content looks like a go migration path is still run by the driver
+	steps := []struct {
+		version int
+		content string
+	}{{1, "go:2.up"}, {2, "go:2.up"}, {1, "go:2.down"}}
+	for _, step := range steps {
+		if err := d.SetVersion(step.version, true); err != nil {
+			t.Fatal(err)
+		}
+		if err := d.Run(strings.NewReader(step.content)); err != nil {
+			t.Fatal(err)
+		}
+		if err := d.SetVersion(step.version, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !slices.Equal(drv.runs, []string{"go:2.up"}) || !slices.Equal(calls, []string{"up", "down"}) {
+		t.Fatalf("unexpected runs: %v, calls: %v", drv.runs, calls)
+	}
+
+	// outside the run loop the migration is routed by the given version
+	if err := runMigration(d, 2, migratesource.Up, []byte("go:2.up")); err != nil {
+		t.Fatal(err)
+	}
+	if err := runMigration(d, 1, migratesource.Up, []byte("SELECT 1")); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(drv.runs, []string{"go:2.up", "SELECT 1"}) || !slices.Equal(calls, []string{"up", "down", "up"}) {
+		t.Fatalf("unexpected runs: %v, calls: %v", drv.runs, calls)
+	}
+}
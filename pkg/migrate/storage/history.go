@@ -0,0 +1,172 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/Borislavv/go-migrate/pkg/migrate/source"
+	"github.com/golang-migrate/migrate/v4/database"
+	"io"
+	"os"
+	"time"
+)
+
+var ErrHistoryIsNotEnabled = errors.New("migrations history is not enabled")
+
+// HistoryDisabled is the history table (collection, key) name which disables the history,
+// it's required by the programmatic configs where the empty name is replaced by the default one.
+const HistoryDisabled = "-"
+
+const (
+	HistoryUp   = "up"
+	HistoryDown = "down"
+	// HistoryRepair marks the repairs of failed migrations (see Repair), the checksum is non-empty
+	// when the down file of the failed migration was executed.
+	HistoryRepair = "repair"
+)
+
+// HistoryEntry describes a single executed migration, failed attempts have a non-empty Error.
+type HistoryEntry struct {
+	Version uint   `json:"version"`
+	Name    string `json:"name"`
+	// Direction is HistoryUp, HistoryDown or HistoryRepair.
+	Direction string `json:"direction"`
+	// Checksum of the executed file (see source.Checksum), it's empty when the file is missing (e.g. no down file).
+	Checksum   string        `json:"checksum"`
+	StartedAt  time.Time     `json:"startedAt"`
+	FinishedAt time.Time     `json:"finishedAt"`
+	Duration   time.Duration `json:"duration"`
+	Host       string        `json:"host"`
+	AppVersion string        `json:"appVersion"`
+	Error      string        `json:"error,omitempty"`
+}
+
+// History is the append-only log of the executed migrations kept by a storage next to its version table.
+type History interface {
+	Append(ctx context.Context, entry HistoryEntry) error
+	// Entries returns all entries ordered by the start time.
+	Entries(ctx context.Context) ([]HistoryEntry, error)
+}
+
+// Historian is implemented by storages which record the executed migrations into the History.
+type Historian interface {
+	History(ctx context.Context) ([]HistoryEntry, error)
+}
+
+// IsHistoryEnabled reports whether the history table (collection, key) name enables the history.
+func IsHistoryEnabled(name string) bool {
+	return name != "" && name != HistoryDisabled
+}
+
+type appVersionKey struct{}
+
+// WithAppVersion returns ctx which carries the application version recorded into the history.
+func WithAppVersion(ctx context.Context, version string) context.Context {
+	return context.WithValue(ctx, appVersionKey{}, version)
+}
+
+// AppVersion returns the application version carried by ctx (see WithAppVersion).
+func AppVersion(ctx context.Context) string {
+	version, _ := ctx.Value(appVersionKey{}).(string)
+	return version
+}
+
+// Record wraps the migrate driver so each migration it executes is appended to the history,
+// the names of the recorded migrations are taken from the given ones. The driver is returned as is without history.
+func Record(ctx context.Context, d database.Driver, history History, migrations []source.Migration) database.Driver {
+	if history == nil {
+		return d
+	}
+
+	names := make(map[uint]string, len(migrations))
+	for _, migration := range migrations {
+		names[migration.Version] = migration.Name
+	}
+	host, _ := os.Hostname()
+
+	return &recorder{
+		Driver:  d,
+		ctx:     ctx,
+		history: history,
+		names:   names,
+		host:    host,
+	}
+}
+
+// recorder relies on the migrate run loop which marks the target version as dirty, runs the migration
+// (unless the file is missing) and marks the target version as clean.
+type recorder struct {
+	database.Driver
+	ctx     context.Context
+	history History
+	names   map[uint]string
+	host    string
+	// pending is the migration which is being executed, nil between migrations
+	pending *HistoryEntry
+}
+
+func (r *recorder) SetVersion(version int, dirty bool) error {
+	if !dirty {
+		if err := r.Driver.SetVersion(version, dirty); err != nil {
+			return err
+		}
+		if r.pending != nil {
+			return r.append(nil)
+		}
+		return nil
+	}
+
+	current, _, err := r.Driver.Version()
+	if err != nil {
+		return err
+	}
+
+	entry := &HistoryEntry{Version: uint(version), Direction: HistoryUp, StartedAt: time.Now()}
+	if version <= current {
+		// the target version of a down migration is the previous one
+		entry.Version, entry.Direction = uint(current), HistoryDown
+	}
+	entry.Name = r.names[entry.Version]
+	r.pending = entry
+
+	return r.Driver.SetVersion(version, dirty)
+}
+
+func (r *recorder) Run(migration io.Reader) error {
+	content, err := io.ReadAll(migration)
+	if err != nil {
+		return err
+	}
+	if r.pending != nil {
+		r.pending.Checksum = source.Checksum(content)
+	}
+
+	if err = r.Driver.Run(bytes.NewReader(content)); err != nil {
+		if r.pending != nil {
+			_ = r.append(err)
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *recorder) append(err error) error {
+	entry := *r.pending
+	r.pending = nil
+
+	return appendEntry(r.ctx, r.history, entry, r.host, err)
+}
+
+// appendEntry completes the entry of the finished migration and appends it to the history.
+func appendEntry(ctx context.Context, history History, entry HistoryEntry, host string, err error) error {
+	entry.FinishedAt = time.Now()
+	entry.Duration = entry.FinishedAt.Sub(entry.StartedAt)
+	entry.Host = host
+	entry.AppVersion = AppVersion(ctx)
+	if err != nil {
+		entry.Error = err.Error()
+	}
+
+	// the failed migration must be recorded even when it failed due to the timeout
+	return history.Append(context.WithoutCancel(ctx), entry)
+}
@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"fmt"
+	"github.com/kelseyhightower/envconfig"
+	"io/fs"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// Instance is a named storage of the registered driver, so several databases of the same kind
+// may be migrated side by side. Its config is loaded from the driver env variables prefixed
+// by EnvPrefix (e.g. BILLING_POSTGRES_HOST for the "billing" instance of the postgres driver).
+type Instance struct {
+	Name   string
+	Driver string
+	FS     fs.FS
+
+	// constructor overrides the registered driver one, see WithStorage.
+	constructor Constructor
+}
+
+// EnvPrefix returns the env variables prefix of the instance, the default instance
+// (which is named after its driver) has no prefix.
+func (i Instance) EnvPrefix() string {
+	if i.Name == i.Driver {
+		return ""
+	}
+	return strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(i.Name))
+}
+
+// LoadEnv loads the config struct by envconfig with the instance prefix (see EnvPrefix). Unlike envconfig.Process
+// the prefixed variables don't fall back to the unprefixed ones, so an omitted BILLING_POSTGRES_HOST leaves
+// the default value instead of taking POSTGRES_HOST of the default instance.
+func LoadEnv[C any](prefix string) (*C, error) {
+	cfg := new(C)
+	if err := envconfig.Process(prefix, cfg); err != nil {
+		return nil, err
+	}
+	if prefix == "" {
+		return cfg, nil
+	}
+
+	v := reflect.ValueOf(cfg).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		key, ok := t.Field(i).Tag.Lookup("envconfig")
+		if !ok {
+			continue
+		}
+		if _, set := os.LookupEnv(strings.ToUpper(prefix + "_" + key)); set {
+			continue
+		}
+
+		field := v.Field(i)
+		field.SetZero()
+		if def, ok := t.Field(i).Tag.Lookup("default"); ok {
+			if err := setDefault(field, def); err != nil {
+				return nil, fmt.Errorf("invalid default of %s: %w", key, err)
+			}
+		}
+	}
+	return cfg, nil
+}
@@ -1,6 +1,9 @@
 package storage
 
-import "context"
+import (
+	"context"
+	"github.com/Borislavv/go-migrate/pkg/migrate/source"
+)
 
 type Factorier interface {
 	Make(ctx context.Context) ([]Storager, error)
@@ -8,8 +11,22 @@ type Factorier interface {
 
 type Storager interface {
 	Name() string
-	Up() error
-	Down() error
-	Force(n int) error
-	Version() (version uint, dirty bool, err error)
+	Up(ctx context.Context) error
+	Down(ctx context.Context) error
+	// Migrate moves the schema up or down to the given version.
+	Migrate(ctx context.Context, version uint) error
+	// Steps applies n migrations forward when n > 0 or rolls back |n| migrations when n < 0.
+	Steps(ctx context.Context, n int) error
+	Force(ctx context.Context, n int) error
+	Version(ctx context.Context) (version uint, dirty bool, err error)
+	// Migrations returns all available migrations ordered by version.
+	Migrations() ([]source.Migration, error)
+	// ReadMigration returns the content of the migration file (see source.Migration Up and Down paths).
+	ReadMigration(path string) ([]byte, error)
+}
+
+// Locker is implemented by storages which support the distributed lock, so only one replica migrates at a time.
+type Locker interface {
+	// Lock blocks until the lock identified by key is acquired or ctx is done, the returned func releases it.
+	Lock(ctx context.Context, key string) (unlock func() error, err error)
 }
@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"os"
+	"strconv"
+	"time"
+)
+
+var ErrInvalidLockTTL = errors.New("lock TTL must be positive")
+
+// LockRetryInterval is the delay between attempts of drivers which poll for the lock.
+var LockRetryInterval = 500 * time.Millisecond
+
+// PollLock calls try until it acquires the lock, fails or ctx is done.
+func PollLock(ctx context.Context, try func(ctx context.Context) (bool, error)) error {
+	ticker := time.NewTicker(LockRetryInterval)
+	defer ticker.Stop()
+
+	for {
+		acquired, err := try(ctx)
+		if err != nil {
+			return err
+		}
+		if acquired {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Heartbeat calls refresh each interval (e.g. to extend the lock TTL) until the returned func is called,
+// the interval must be positive.
+func Heartbeat(interval time.Duration, refresh func(ctx context.Context) error) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				_ = refresh(ctx)
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// LockOwner returns the unique lock owner identifier, it contains the host name and pid for debugging purposes.
+func LockOwner() string {
+	host, _ := os.Hostname()
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return host + ":" + strconv.Itoa(os.Getpid()) + ":" + hex.EncodeToString(b)
+}
@@ -1,6 +1,9 @@
 package mongo
 
-import "github.com/kelseyhightower/envconfig"
+import (
+	"github.com/Borislavv/go-migrate/pkg/migrate/storage"
+	"time"
+)
 
 type Configurator interface {
 	GetMongoHost() string
@@ -8,26 +11,64 @@ type Configurator interface {
 	GetMongoLogin() string
 	GetMongoPassword() string
 	GetMongoDatabase() string
+	GetMongoURI() string
+	GetMongoScheme() string
+	GetMongoAuthSource() string
+	IsMongoTLSEnabled() bool
+	GetMongoTLSCAFile() string
+	GetMongoTLSCertFile() string
+	GetMongoTLSKeyFile() string
+	IsMongoTLSInsecureSkipVerify() bool
+	GetMongoParams() map[string]string
 	GetMongoMigrationsCollection() string
+	GetMongoMigrationsHistoryCollection() string
+	GetMongoLockCollection() string
+	GetMongoLockTTL() time.Duration
+	GetMongoConnectTimeout() time.Duration
+	GetMongoConnectRetries() int
+	GetMongoMigrationsDir() string
+	GetMongoMigrationsTimeout() time.Duration
 	IsMongoMigrationsEnabled() bool
 }
 
 type Config struct {
-	MongoMigrationsEnabled    bool   `envconfig:"MONGO_MIGRATIONS_ENABLED" default:"false"`
-	MongoHost                 string `envconfig:"MONGO_HOST"`
-	MongoPort                 string `envconfig:"MONGO_PORT"`
-	MongoLogin                string `envconfig:"MONGO_LOGIN"`
-	MongoPassword             string `envconfig:"MONGO_PASSWORD"`
-	MongoDatabase             string `envconfig:"MONGO_DATABASE"`
-	MongoMigrationsCollection string `envconfig:"MONGO_MIGRATIONS_COLLECTION" default:"migrationVersions"`
+	MongoMigrationsEnabled           bool              `envconfig:"MONGO_MIGRATIONS_ENABLED" default:"false"`
+	MongoHost                        string            `envconfig:"MONGO_HOST"`
+	MongoPort                        string            `envconfig:"MONGO_PORT"`
+	MongoLogin                       string            `envconfig:"MONGO_LOGIN"`
+	MongoPassword                    string            `envconfig:"MONGO_PASSWORD"`
+	MongoDatabase                    string            `envconfig:"MONGO_DATABASE"`
+	MongoURI                         string            `envconfig:"MONGO_URI"`
+	MongoScheme                      string            `envconfig:"MONGO_SCHEME" default:"mongodb"`
+	MongoAuthSource                  string            `envconfig:"MONGO_AUTH_SOURCE"`
+	MongoTLSEnabled                  bool              `envconfig:"MONGO_TLS_ENABLED" default:"false"`
+	MongoTLSCAFile                   string            `envconfig:"MONGO_TLS_CA_FILE"`
+	MongoTLSCertFile                 string            `envconfig:"MONGO_TLS_CERT_FILE"`
+	MongoTLSKeyFile                  string            `envconfig:"MONGO_TLS_KEY_FILE"`
+	MongoTLSInsecureSkipVerify       bool              `envconfig:"MONGO_TLS_INSECURE_SKIP_VERIFY" default:"false"`
+	MongoParams                      map[string]string `envconfig:"MONGO_PARAMS"`
+	MongoMigrationsCollection        string            `envconfig:"MONGO_MIGRATIONS_COLLECTION" default:"migrationVersions"`
+	MongoMigrationsHistoryCollection string            `envconfig:"MONGO_MIGRATIONS_HISTORY_COLLECTION" default:"migrationHistory"`
+	MongoLockCollection              string            `envconfig:"MONGO_LOCK_COLLECTION" default:"migrationLocks"`
+	MongoLockTTL                     time.Duration     `envconfig:"MONGO_LOCK_TTL" default:"1m"`
+	MongoConnectTimeout              time.Duration     `envconfig:"MONGO_CONNECT_TIMEOUT" default:"0"`
+	MongoConnectRetries              int               `envconfig:"MONGO_CONNECT_RETRIES" default:"0"`
+	MongoMigrationsDir               string            `envconfig:"MONGO_MIGRATIONS_DIR" default:"migrations"`
+	MongoMigrationsTimeout           time.Duration     `envconfig:"MONGO_MIGRATIONS_TIMEOUT" default:"0"`
+}
+
+// DefaultConfig returns the config with the same defaults as the env one.
+func DefaultConfig() *Config {
+	return storage.SetDefaults(new(Config))
 }
 
 func Load() (*Config, error) {
-	cfg := new(Config)
-	if err := envconfig.Process("", cfg); err != nil {
-		return nil, err
-	}
-	return cfg, nil
+	return LoadWithPrefix("")
+}
+
+// LoadWithPrefix loads the config of a named storage instance, e.g. BILLING_MONGO_HOST for the "BILLING" prefix.
+func LoadWithPrefix(prefix string) (*Config, error) {
+	return storage.LoadEnv[Config](prefix)
 }
 
 func (c *Config) IsMongoMigrationsEnabled() bool {
@@ -54,6 +95,74 @@ func (c *Config) GetMongoDatabase() string {
 	return c.MongoDatabase
 }
 
+func (c *Config) GetMongoURI() string {
+	return c.MongoURI
+}
+
+func (c *Config) GetMongoScheme() string {
+	return c.MongoScheme
+}
+
+// GetMongoAuthSource returns the authentication database, it's the migrated database by default.
+func (c *Config) GetMongoAuthSource() string {
+	if c.MongoAuthSource == "" {
+		return c.MongoDatabase
+	}
+	return c.MongoAuthSource
+}
+
+func (c *Config) IsMongoTLSEnabled() bool {
+	return c.MongoTLSEnabled
+}
+
+func (c *Config) GetMongoTLSCAFile() string {
+	return c.MongoTLSCAFile
+}
+
+func (c *Config) GetMongoTLSCertFile() string {
+	return c.MongoTLSCertFile
+}
+
+func (c *Config) GetMongoTLSKeyFile() string {
+	return c.MongoTLSKeyFile
+}
+
+func (c *Config) IsMongoTLSInsecureSkipVerify() bool {
+	return c.MongoTLSInsecureSkipVerify
+}
+
+func (c *Config) GetMongoParams() map[string]string {
+	return c.MongoParams
+}
+
 func (c *Config) GetMongoMigrationsCollection() string {
 	return c.MongoMigrationsCollection
 }
+
+func (c *Config) GetMongoMigrationsHistoryCollection() string {
+	return c.MongoMigrationsHistoryCollection
+}
+
+func (c *Config) GetMongoLockCollection() string {
+	return c.MongoLockCollection
+}
+
+func (c *Config) GetMongoLockTTL() time.Duration {
+	return c.MongoLockTTL
+}
+
+func (c *Config) GetMongoConnectTimeout() time.Duration {
+	return c.MongoConnectTimeout
+}
+
+func (c *Config) GetMongoConnectRetries() int {
+	return c.MongoConnectRetries
+}
+
+func (c *Config) GetMongoMigrationsDir() string {
+	return c.MongoMigrationsDir
+}
+
+func (c *Config) GetMongoMigrationsTimeout() time.Duration {
+	return c.MongoMigrationsTimeout
+}
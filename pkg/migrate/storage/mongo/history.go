@@ -0,0 +1,74 @@
+package mongo
+
+import (
+	"context"
+	"github.com/Borislavv/go-migrate/pkg/migrate/storage"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+// historyDocument is the storage.HistoryEntry stored in the history collection.
+type historyDocument struct {
+	Version    int64     `bson:"version"`
+	Name       string    `bson:"name"`
+	Direction  string    `bson:"direction"`
+	Checksum   string    `bson:"checksum"`
+	StartedAt  time.Time `bson:"startedAt"`
+	FinishedAt time.Time `bson:"finishedAt"`
+	DurationMs int64     `bson:"durationMs"`
+	Host       string    `bson:"host"`
+	AppVersion string    `bson:"appVersion"`
+	Error      string    `bson:"error,omitempty"`
+}
+
+// history keeps the history entries in the collection, see storage.History.
+type history struct {
+	collection *mongo.Collection
+}
+
+func (h *history) Append(ctx context.Context, entry storage.HistoryEntry) error {
+	_, err := h.collection.InsertOne(ctx, historyDocument{
+		Version:    int64(entry.Version),
+		Name:       entry.Name,
+		Direction:  entry.Direction,
+		Checksum:   entry.Checksum,
+		StartedAt:  entry.StartedAt,
+		FinishedAt: entry.FinishedAt,
+		DurationMs: entry.Duration.Milliseconds(),
+		Host:       entry.Host,
+		AppVersion: entry.AppVersion,
+		Error:      entry.Error,
+	})
+	return err
+}
+
+func (h *history) Entries(ctx context.Context) ([]storage.HistoryEntry, error) {
+	cursor, err := h.collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "startedAt", Value: 1}, {Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	var documents []historyDocument
+	if err = cursor.All(ctx, &documents); err != nil {
+		return nil, err
+	}
+
+	entries := make([]storage.HistoryEntry, 0, len(documents))
+	for _, d := range documents {
+		entries = append(entries, storage.HistoryEntry{
+			Version:    uint(d.Version),
+			Name:       d.Name,
+			Direction:  d.Direction,
+			Checksum:   d.Checksum,
+			StartedAt:  d.StartedAt,
+			FinishedAt: d.FinishedAt,
+			Duration:   time.Duration(d.DurationMs) * time.Millisecond,
+			Host:       d.Host,
+			AppVersion: d.AppVersion,
+			Error:      d.Error,
+		})
+	}
+	return entries, nil
+}
@@ -2,37 +2,52 @@ package mongo
 
 import (
 	"context"
-	"embed"
 	"errors"
 	"fmt"
+	"github.com/Borislavv/go-migrate/pkg/migrate/source"
+	"github.com/Borislavv/go-migrate/pkg/migrate/storage"
 	"github.com/Borislavv/migrate/v4"
 	"github.com/Borislavv/migrate/v4/database/mongodb"
-	_ "github.com/Borislavv/migrate/v4/source/file"
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
-	"os"
-	"path/filepath"
+	"io/fs"
+	"net"
+	"net/url"
+	"time"
 )
 
-const DriverName = "mongodb"
+const (
+	DriverName = "mongodb"
+	// MigrationsExt is the extension of the migration files.
+	MigrationsExt = "json"
+)
 
 type Mongo struct {
-	db  *mongo.Database
-	cfg Configurator
-	fs  embed.FS
-}
-
-func New(ctx context.Context, cfg Configurator, fs embed.FS) (*Mongo, error) {
-	clientOptions := options.Client().ApplyURI(fmt.Sprintf(
-		"%s://%s:%s@%s:%s/?authSource=%s",
-		DriverName,
-		cfg.GetMongoLogin(),
-		cfg.GetMongoPassword(),
-		cfg.GetMongoHost(),
-		cfg.GetMongoPort(),
-		cfg.GetMongoDatabase(),
-	))
+	name    string
+	db      *mongo.Database
+	cfg     Configurator
+	fs      fs.FS
+	history storage.History
+}
+
+func New(ctx context.Context, cfg Configurator, fsys fs.FS) (*Mongo, error) {
+	clientOptions := options.Client().ApplyURI(uri(cfg))
+	if cfg.IsMongoTLSEnabled() {
+		tlsCfg, err := storage.NewTLSConfig(
+			cfg.GetMongoTLSCAFile(),
+			cfg.GetMongoTLSCertFile(),
+			cfg.GetMongoTLSKeyFile(),
+			cfg.IsMongoTLSInsecureSkipVerify(),
+		)
+		if err != nil {
+			return nil, err
+		}
+		clientOptions.SetTLSConfig(tlsCfg)
+	}
 
 	mongoClient, err := mongo.Connect(ctx, clientOptions)
 	if err != nil {
@@ -44,49 +59,89 @@ func New(ctx context.Context, cfg Configurator, fs embed.FS) (*Mongo, error) {
 		_ = mongoClient.Disconnect(ctx)
 	}()
 
-	if err = mongoClient.Ping(ctx, readpref.Primary()); err != nil {
+	return NewWithClient(ctx, mongoClient, cfg, fsys)
+}
+
+// NewWithClient makes the storage on top of the existing client, which is owned (and disconnected) by the caller.
+func NewWithClient(ctx context.Context, client *mongo.Client, cfg Configurator, fsys fs.FS) (*Mongo, error) {
+	if err := storage.Connect(ctx, cfg.GetMongoConnectRetries(), cfg.GetMongoConnectTimeout(), func(ctx context.Context) error {
+		return client.Ping(ctx, readpref.Primary())
+	}); err != nil {
 		return nil, err
 	}
 
+	db := client.Database(cfg.GetMongoDatabase())
+	var h storage.History
+	if collection := cfg.GetMongoMigrationsHistoryCollection(); storage.IsHistoryEnabled(collection) {
+		h = &history{collection: db.Collection(collection)}
+	}
+
 	return &Mongo{
-		db:  mongoClient.Database(cfg.GetMongoDatabase()),
-		cfg: cfg,
-		fs:  fs,
+		name:    DriverName,
+		db:      db,
+		cfg:     cfg,
+		fs:      fsys,
+		history: h,
 	}, nil
 }
 
 func (m *Mongo) Name() string {
-	return DriverName
+	return m.name
 }
 
-func (m *Mongo) Up() error {
-	s, err := m.migrate()
+func (m *Mongo) Up(ctx context.Context) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	s, err := m.migrate(ctx)
 	if err != nil {
 		return err
 	}
 
-	if err = s.Up(); err != nil {
+	return storage.Run(ctx, s, s.Up)
+}
+
+func (m *Mongo) Down(ctx context.Context) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	s, err := m.migrate(ctx)
+	if err != nil {
 		return err
 	}
 
-	return nil
+	return storage.Run(ctx, s, s.Down)
 }
 
-func (m *Mongo) Down() error {
-	s, err := m.migrate()
+func (m *Mongo) Migrate(ctx context.Context, version uint) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	s, err := m.migrate(ctx)
 	if err != nil {
 		return err
 	}
 
-	if err = s.Down(); err != nil {
+	return storage.Run(ctx, s, func() error { return s.Migrate(version) })
+}
+
+func (m *Mongo) Steps(ctx context.Context, n int) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	s, err := m.migrate(ctx)
+	if err != nil {
 		return err
 	}
 
-	return nil
+	return storage.Run(ctx, s, func() error { return s.Steps(n) })
 }
 
-func (m *Mongo) Force(n int) error {
-	s, err := m.migrate()
+func (m *Mongo) Force(ctx context.Context, n int) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	s, err := m.migrate(ctx)
 	if err != nil {
 		return err
 	}
@@ -95,11 +150,14 @@ func (m *Mongo) Force(n int) error {
 		return err
 	}
 
-	return nil
+	return ctx.Err()
 }
 
-func (m *Mongo) Version() (version uint, dirty bool, err error) {
-	s, err := m.migrate()
+func (m *Mongo) Version(ctx context.Context) (version uint, dirty bool, err error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	s, err := m.migrate(ctx)
 	if err != nil {
 		return 0, true, err
 	}
@@ -107,42 +165,203 @@ func (m *Mongo) Version() (version uint, dirty bool, err error) {
 	return s.Version()
 }
 
-func (m *Mongo) migrate() (*migrate.Migrate, error) {
-	if m.db == nil {
-		return nil, errors.New("the underlying database pointer is not initialized, you need to call the 'New' method first")
+// Migrations returns all migrations available in the migrations fs and the registered Go ones ordered by version.
+func (m *Mongo) Migrations() ([]source.Migration, error) {
+	migrations, err := source.List(m.fs, m.cfg.GetMongoMigrationsDir())
+	if err != nil {
+		return nil, err
 	}
 
-	d, err := mongodb.WithInstance(m.db.Client(), &mongodb.Config{
-		DatabaseName:         m.db.Name(),
-		MigrationsCollection: m.cfg.GetMongoMigrationsCollection(),
+	return storage.WithGoMigrations(m.name, migrations)
+}
+
+// ReadMigration returns the content of the migration file by its path in the migrations fs (or of the Go migration).
+func (m *Mongo) ReadMigration(path string) ([]byte, error) {
+	if content, ok := storage.ReadGoMigration(m.name, path); ok {
+		return content, nil
+	}
+	return fs.ReadFile(m.fs, path)
+}
+
+// History returns the executed migrations recorded into MONGO_MIGRATIONS_HISTORY_COLLECTION,
+// the history is disabled when it's empty or storage.HistoryDisabled, see storage.Historian.
+func (m *Mongo) History(ctx context.Context) ([]storage.HistoryEntry, error) {
+	if m.history == nil {
+		return nil, storage.ErrHistoryIsNotEnabled
+	}
+
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	return m.history.Entries(ctx)
+}
+
+// Apply executes the up file of the migration without moving the version, see storage.Applier.
+func (m *Mongo) Apply(ctx context.Context, migration source.Migration) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	content, err := m.ReadMigration(migration.Up)
+	if err != nil {
+		return err
+	}
+
+	d, err := m.driver(ctx)
+	if err != nil {
+		return err
+	}
+
+	return storage.Apply(ctx, d, m.history, migration, content)
+}
+
+// Repair executes the down file of the failed migration (if requested) and forces the clean version,
+// see storage.Repairer.
+func (m *Mongo) Repair(ctx context.Context, failed source.Migration, version int, down bool) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	var content []byte
+	if down && failed.Down != "" {
+		var err error
+		if content, err = m.ReadMigration(failed.Down); err != nil {
+			return err
+		}
+	}
+
+	d, err := m.driver(ctx)
+	if err != nil {
+		return err
+	}
+
+	return storage.Repair(ctx, d, m.history, failed, content, version)
+}
+
+// Lock acquires the lock document which expires after MONGO_LOCK_TTL unless it's extended by the holder,
+// so the lock of a crashed replica is taken over once it expires, see storage.Locker.
+func (m *Mongo) Lock(ctx context.Context, key string) (unlock func() error, err error) {
+	ttl := m.cfg.GetMongoLockTTL()
+	if ttl <= 0 {
+		return nil, fmt.Errorf("%w: MONGO_LOCK_TTL is %s", storage.ErrInvalidLockTTL, ttl)
+	}
+
+	collection := m.db.Collection(m.cfg.GetMongoLockCollection())
+	owner := storage.LockOwner()
+
+	// the TTL index cleans up the expired locks which were never taken over
+	if _, err = collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	}); err != nil {
+		return nil, err
+	}
+
+	if err = storage.PollLock(ctx, func(ctx context.Context) (bool, error) {
+		now := time.Now()
+		// matches only an expired lock, otherwise the upsert fails on the _id duplicate
+		_, err := collection.UpdateOne(ctx,
+			bson.M{"_id": key, "expiresAt": bson.M{"$lt": now}},
+			bson.M{"$set": bson.M{"owner": owner, "expiresAt": now.Add(ttl)}},
+			options.Update().SetUpsert(true),
+		)
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return err == nil, err
+	}); err != nil {
+		return nil, err
+	}
+
+	stop := storage.Heartbeat(ttl/3, func(ctx context.Context) error {
+		_, err := collection.UpdateOne(ctx,
+			bson.M{"_id": key, "owner": owner},
+			bson.M{"$set": bson.M{"expiresAt": time.Now().Add(ttl)}},
+		)
+		return err
 	})
+
+	return func() error {
+		stop()
+		_, err := collection.DeleteOne(context.Background(), bson.M{"_id": key, "owner": owner})
+		return err
+	}, nil
+}
+
+// withTimeout limits ctx by the configured migrations timeout (if any).
+func (m *Mongo) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return storage.WithTimeout(ctx, m.cfg.GetMongoMigrationsTimeout())
+}
+
+func (m *Mongo) migrate(ctx context.Context) (*migrate.Migrate, error) {
+	migrations, err := m.Migrations()
 	if err != nil {
 		return nil, err
 	}
 
-	rootDir, err := os.Getwd()
+	d, err := m.driver(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get current working directory: %w", err)
+		return nil, err
 	}
 
-	destDir := filepath.Join(rootDir, "tmp", DriverName)
-	if err = os.RemoveAll(destDir); err != nil {
-		return nil, fmt.Errorf("could not clear temporary MongoDB migrations directory: %w", err)
+	src, err := iofs.New(m.fs, m.cfg.GetMongoMigrationsDir())
+	if err != nil {
+		return nil, fmt.Errorf("could not open MongoDB migrations fs: %w", err)
 	}
 
-	if err = os.MkdirAll(destDir, 0777); err != nil {
-		return nil, fmt.Errorf("could not create MongoDB migrations directory: %w", err)
+	s, err := migrate.NewWithInstance("iofs", storage.GoSource(src, m.name, migrations), DriverName, storage.Record(ctx, d, m.history, migrations))
+	if err != nil {
+		return nil, err
 	}
 
-	if err = os.CopyFS(destDir, m.fs); err != nil {
-		return nil, fmt.Errorf("could not copy MongoDB migrations fs: %w", err)
+	return s, nil
+}
+
+// driver makes the migrate database driver, it must not be closed as it would close the shared client.
+func (m *Mongo) driver(ctx context.Context) (database.Driver, error) {
+	if m.db == nil {
+		return nil, errors.New("the underlying database pointer is not initialized, you need to call the 'New' method first")
 	}
 
-	migrationsDir := filepath.Join(destDir, "migrations")
-	s, err := migrate.NewWithDatabaseInstance("file://"+migrationsDir, DriverName, d)
+	d, err := mongodb.WithInstance(m.db.Client(), &mongodb.Config{
+		DatabaseName:         m.db.Name(),
+		MigrationsCollection: m.cfg.GetMongoMigrationsCollection(),
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	return s, nil
+	return storage.GoDriver(ctx, d, m.name, storage.HandleExecutor(m.db)), nil
+}
+
+// uri returns MONGO_URI as is or builds the connection URI with escaped credentials, auth source and extra params
+// (e.g. replicaSet), the port is omitted for mongodb+srv and when it's empty (MONGO_HOST may list several host:port pairs).
+func uri(cfg Configurator) string {
+	if cfg.GetMongoURI() != "" {
+		return cfg.GetMongoURI()
+	}
+
+	query := url.Values{}
+	for key, value := range cfg.GetMongoParams() {
+		query.Set(key, value)
+	}
+	if cfg.GetMongoAuthSource() != "" {
+		query.Set("authSource", cfg.GetMongoAuthSource())
+	}
+
+	u := &url.URL{
+		Scheme:   cfg.GetMongoScheme(),
+		Host:     cfg.GetMongoHost(),
+		Path:     "/",
+		RawQuery: query.Encode(),
+	}
+	if u.Scheme == "" {
+		u.Scheme = DriverName
+	}
+	if cfg.GetMongoPort() != "" && u.Scheme == DriverName {
+		u.Host = net.JoinHostPort(cfg.GetMongoHost(), cfg.GetMongoPort())
+	}
+	if cfg.GetMongoLogin() != "" {
+		u.User = url.UserPassword(cfg.GetMongoLogin(), cfg.GetMongoPassword())
+	}
+
+	return u.String()
 }
@@ -1,6 +1,9 @@
 package mysql
 
-import "github.com/kelseyhightower/envconfig"
+import (
+	"github.com/Borislavv/go-migrate/pkg/migrate/storage"
+	"time"
+)
 
 type Configurator interface {
 	IsMySQLMigrationsEnabled() bool
@@ -9,25 +12,55 @@ type Configurator interface {
 	GetMySQLDatabase() string
 	GetMySQLHost() string
 	GetMySQLPort() string
+	GetMySQLDSN() string
+	IsMySQLTLSEnabled() bool
+	GetMySQLTLSCAFile() string
+	GetMySQLTLSCertFile() string
+	GetMySQLTLSKeyFile() string
+	IsMySQLTLSInsecureSkipVerify() bool
+	GetMySQLParams() map[string]string
 	GetMySQLMigrationsTable() string
+	GetMySQLMigrationsHistoryTable() string
+	GetMySQLConnectTimeout() time.Duration
+	GetMySQLConnectRetries() int
+	GetMySQLMigrationsDir() string
+	GetMySQLMigrationsTimeout() time.Duration
 }
 
 type Config struct {
-	MySQLMigrationsEnabled bool   `envconfig:"MYSQL_MIGRATIONS_ENABLED" default:"false"`
-	MySQLHost              string `envconfig:"MYSQL_HOST"`
-	MySQLPort              string `envconfig:"MYSQL_PORT"`
-	MySQLUsername          string `envconfig:"MYSQL_LOGIN"`
-	MySQLPassword          string `envconfig:"MYSQL_PASSWORD"`
-	MySQLDatabase          string `envconfig:"MYSQL_DATABASE"`
-	MySQLMigrationsTable   string `envconfig:"MYSQL_MIGRATIONS_TABLE" default:"migration_versions"`
+	MySQLMigrationsEnabled      bool              `envconfig:"MYSQL_MIGRATIONS_ENABLED" default:"false"`
+	MySQLHost                   string            `envconfig:"MYSQL_HOST"`
+	MySQLPort                   string            `envconfig:"MYSQL_PORT"`
+	MySQLUsername               string            `envconfig:"MYSQL_LOGIN"`
+	MySQLPassword               string            `envconfig:"MYSQL_PASSWORD"`
+	MySQLDatabase               string            `envconfig:"MYSQL_DATABASE"`
+	MySQLDSN                    string            `envconfig:"MYSQL_DSN"`
+	MySQLTLSEnabled             bool              `envconfig:"MYSQL_TLS_ENABLED" default:"false"`
+	MySQLTLSCAFile              string            `envconfig:"MYSQL_TLS_CA_FILE"`
+	MySQLTLSCertFile            string            `envconfig:"MYSQL_TLS_CERT_FILE"`
+	MySQLTLSKeyFile             string            `envconfig:"MYSQL_TLS_KEY_FILE"`
+	MySQLTLSInsecureSkipVerify  bool              `envconfig:"MYSQL_TLS_INSECURE_SKIP_VERIFY" default:"false"`
+	MySQLParams                 map[string]string `envconfig:"MYSQL_PARAMS"`
+	MySQLMigrationsTable        string            `envconfig:"MYSQL_MIGRATIONS_TABLE" default:"migration_versions"`
+	MySQLMigrationsHistoryTable string            `envconfig:"MYSQL_MIGRATIONS_HISTORY_TABLE" default:"migration_history"`
+	MySQLConnectTimeout         time.Duration     `envconfig:"MYSQL_CONNECT_TIMEOUT" default:"0"`
+	MySQLConnectRetries         int               `envconfig:"MYSQL_CONNECT_RETRIES" default:"0"`
+	MySQLMigrationsDir          string            `envconfig:"MYSQL_MIGRATIONS_DIR" default:"migrations"`
+	MySQLMigrationsTimeout      time.Duration     `envconfig:"MYSQL_MIGRATIONS_TIMEOUT" default:"0"`
+}
+
+// DefaultConfig returns the config with the same defaults as the env one.
+func DefaultConfig() *Config {
+	return storage.SetDefaults(new(Config))
 }
 
 func Load() (*Config, error) {
-	cfg := new(Config)
-	if err := envconfig.Process("", cfg); err != nil {
-		return nil, err
-	}
-	return cfg, nil
+	return LoadWithPrefix("")
+}
+
+// LoadWithPrefix loads the config of a named storage instance, e.g. BILLING_MYSQL_HOST for the "BILLING" prefix.
+func LoadWithPrefix(prefix string) (*Config, error) {
+	return storage.LoadEnv[Config](prefix)
 }
 
 func (c *Config) IsMySQLMigrationsEnabled() bool {
@@ -54,6 +87,54 @@ func (c *Config) GetMySQLDatabase() string {
 	return c.MySQLDatabase
 }
 
+func (c *Config) GetMySQLDSN() string {
+	return c.MySQLDSN
+}
+
+func (c *Config) IsMySQLTLSEnabled() bool {
+	return c.MySQLTLSEnabled
+}
+
+func (c *Config) GetMySQLTLSCAFile() string {
+	return c.MySQLTLSCAFile
+}
+
+func (c *Config) GetMySQLTLSCertFile() string {
+	return c.MySQLTLSCertFile
+}
+
+func (c *Config) GetMySQLTLSKeyFile() string {
+	return c.MySQLTLSKeyFile
+}
+
+func (c *Config) IsMySQLTLSInsecureSkipVerify() bool {
+	return c.MySQLTLSInsecureSkipVerify
+}
+
+func (c *Config) GetMySQLParams() map[string]string {
+	return c.MySQLParams
+}
+
 func (c *Config) GetMySQLMigrationsTable() string {
 	return c.MySQLMigrationsTable
 }
+
+func (c *Config) GetMySQLMigrationsHistoryTable() string {
+	return c.MySQLMigrationsHistoryTable
+}
+
+func (c *Config) GetMySQLConnectTimeout() time.Duration {
+	return c.MySQLConnectTimeout
+}
+
+func (c *Config) GetMySQLConnectRetries() int {
+	return c.MySQLConnectRetries
+}
+
+func (c *Config) GetMySQLMigrationsDir() string {
+	return c.MySQLMigrationsDir
+}
+
+func (c *Config) GetMySQLMigrationsTimeout() time.Duration {
+	return c.MySQLMigrationsTimeout
+}
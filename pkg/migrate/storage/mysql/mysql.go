@@ -0,0 +1,405 @@
+package mysql
+
+import (
+	"context"
+	"crypto/sha1"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/Borislavv/go-migrate/pkg/migrate/source"
+	"github.com/Borislavv/go-migrate/pkg/migrate/storage"
+	"github.com/Borislavv/migrate/v4"
+	"github.com/Borislavv/migrate/v4/database/mysql"
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"io/fs"
+	"net"
+	"net/url"
+	"strings"
+)
+
+const (
+	DriverName = "mysql"
+	// MigrationsExt is the extension of the migration files.
+	MigrationsExt = "sql"
+)
+
+type MySQL struct {
+	name    string
+	db      *sql.DB
+	cfg     Configurator
+	fs      fs.FS
+	history storage.History
+}
+
+func New(ctx context.Context, cfg Configurator, fsys fs.FS) (*MySQL, error) {
+	return NewNamed(ctx, DriverName, cfg, fsys)
+}
+
+// NewNamed makes the storage of the named instance, its TLS config (if enabled) is registered under the instance name,
+// so the instances don't override each other's one.
+func NewNamed(ctx context.Context, name string, cfg Configurator, fsys fs.FS) (*MySQL, error) {
+	dsn, err := dsn(name, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open(DriverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		<-ctx.Done()
+		_ = db.Close()
+	}()
+
+	m, err := NewWithDB(ctx, db, cfg, fsys)
+	if err != nil {
+		return nil, err
+	}
+	m.name = name
+
+	return m, nil
+}
+
+// NewWithDB makes the storage on top of the existing connection pool, which is owned (and closed) by the caller.
+// The pool must allow at least storage.MinPoolConns open connections (storage.MinLockPoolConns with the distributed
+// lock enabled), a smaller one is rejected by storage.ErrPoolTooSmall.
+func NewWithDB(ctx context.Context, db *sql.DB, cfg Configurator, fsys fs.FS) (*MySQL, error) {
+	if err := storage.CheckPool(db, storage.MinPoolConns); err != nil {
+		return nil, err
+	}
+	if err := storage.Connect(ctx, cfg.GetMySQLConnectRetries(), cfg.GetMySQLConnectTimeout(), db.PingContext); err != nil {
+		return nil, err
+	}
+
+	var h storage.History
+	if table := cfg.GetMySQLMigrationsHistoryTable(); storage.IsHistoryEnabled(table) {
+		h = storage.NewSQLHistory(db, table, storage.SQLHistoryDDL("DATETIME(6)"), false)
+	}
+
+	return &MySQL{
+		name:    DriverName,
+		db:      db,
+		cfg:     cfg,
+		fs:      fsys,
+		history: h,
+	}, nil
+}
+
+func (m *MySQL) Name() string {
+	return m.name
+}
+
+func (m *MySQL) Up(ctx context.Context) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	s, err := m.migrate(ctx)
+	if err != nil {
+		return err
+	}
+	defer release(s)
+
+	return storage.Run(ctx, s, s.Up)
+}
+
+func (m *MySQL) Down(ctx context.Context) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	s, err := m.migrate(ctx)
+	if err != nil {
+		return err
+	}
+	defer release(s)
+
+	return storage.Run(ctx, s, s.Down)
+}
+
+func (m *MySQL) Migrate(ctx context.Context, version uint) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	s, err := m.migrate(ctx)
+	if err != nil {
+		return err
+	}
+	defer release(s)
+
+	return storage.Run(ctx, s, func() error { return s.Migrate(version) })
+}
+
+func (m *MySQL) Steps(ctx context.Context, n int) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	s, err := m.migrate(ctx)
+	if err != nil {
+		return err
+	}
+	defer release(s)
+
+	return storage.Run(ctx, s, func() error { return s.Steps(n) })
+}
+
+func (m *MySQL) Force(ctx context.Context, n int) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	s, err := m.migrate(ctx)
+	if err != nil {
+		return err
+	}
+	defer release(s)
+
+	if err = s.Force(n); err != nil {
+		return err
+	}
+
+	return ctx.Err()
+}
+
+func (m *MySQL) Version(ctx context.Context) (version uint, dirty bool, err error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	s, err := m.migrate(ctx)
+	if err != nil {
+		return 0, true, err
+	}
+	defer release(s)
+
+	return s.Version()
+}
+
+// Migrations returns all migrations available in the migrations fs and the registered Go ones ordered by version.
+func (m *MySQL) Migrations() ([]source.Migration, error) {
+	migrations, err := source.List(m.fs, m.cfg.GetMySQLMigrationsDir())
+	if err != nil {
+		return nil, err
+	}
+
+	return storage.WithGoMigrations(m.name, migrations)
+}
+
+// ReadMigration returns the content of the migration file by its path in the migrations fs (or of the Go migration).
+func (m *MySQL) ReadMigration(path string) ([]byte, error) {
+	if content, ok := storage.ReadGoMigration(m.name, path); ok {
+		return content, nil
+	}
+	return fs.ReadFile(m.fs, path)
+}
+
+// History returns the executed migrations recorded into MYSQL_MIGRATIONS_HISTORY_TABLE,
+// the history is disabled when it's empty or storage.HistoryDisabled, see storage.Historian.
+func (m *MySQL) History(ctx context.Context) ([]storage.HistoryEntry, error) {
+	if m.history == nil {
+		return nil, storage.ErrHistoryIsNotEnabled
+	}
+
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	return m.history.Entries(ctx)
+}
+
+// Apply executes the up file of the migration without moving the version, see storage.Applier.
+func (m *MySQL) Apply(ctx context.Context, migration source.Migration) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	content, err := m.ReadMigration(migration.Up)
+	if err != nil {
+		return err
+	}
+
+	d, err := m.driver(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = d.Close() }()
+
+	return storage.Apply(ctx, d, m.history, migration, content)
+}
+
+// Repair executes the down file of the failed migration (if requested) and forces the clean version,
+// see storage.Repairer.
+func (m *MySQL) Repair(ctx context.Context, failed source.Migration, version int, down bool) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	var content []byte
+	if down && failed.Down != "" {
+		var err error
+		if content, err = m.ReadMigration(failed.Down); err != nil {
+			return err
+		}
+	}
+
+	d, err := m.driver(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = d.Close() }()
+
+	return storage.Repair(ctx, d, m.history, failed, content, version)
+}
+
+// Lock acquires the named GET_LOCK lock on a dedicated connection, see storage.Locker.
+func (m *MySQL) Lock(ctx context.Context, key string) (unlock func() error, err error) {
+	if err = storage.CheckPool(m.db, storage.MinLockPoolConns); err != nil {
+		return nil, err
+	}
+
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	name := lockName(key)
+	if err = storage.PollLock(ctx, func(ctx context.Context) (bool, error) {
+		var acquired sql.NullInt64
+		err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, 0)", name).Scan(&acquired)
+		return acquired.Valid && acquired.Int64 == 1, err
+	}); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	return func() error {
+		defer func() { _ = conn.Close() }()
+		_, err := conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", name)
+		return err
+	}, nil
+}
+
+// withTimeout limits ctx by the configured migrations timeout (if any).
+func (m *MySQL) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return storage.WithTimeout(ctx, m.cfg.GetMySQLMigrationsTimeout())
+}
+
+func (m *MySQL) migrate(ctx context.Context) (*migrate.Migrate, error) {
+	migrations, err := m.Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := m.driver(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	src, err := iofs.New(m.fs, m.cfg.GetMySQLMigrationsDir())
+	if err != nil {
+		_ = d.Close()
+		return nil, fmt.Errorf("could not open MySQL migrations fs: %w", err)
+	}
+
+	s, err := migrate.NewWithInstance("iofs", storage.GoSource(src, m.name, migrations), DriverName, storage.Record(ctx, d, m.history, migrations))
+	if err != nil {
+		_ = d.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// driver makes the migrate database driver, it must be closed to return the dedicated connection to the pool.
+func (m *MySQL) driver(ctx context.Context) (database.Driver, error) {
+	if m.db == nil {
+		return nil, errors.New("the underlying database pointer is not initialized, you need to call the 'New' method first")
+	}
+
+	// a dedicated connection is returned to the pool once the driver is closed, the pool itself is left open
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := mysql.WithConnection(ctx, conn, &mysql.Config{
+		DatabaseName:    m.cfg.GetMySQLDatabase(),
+		MigrationsTable: m.cfg.GetMySQLMigrationsTable(),
+	})
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	return storage.GoDriver(ctx, d, m.name, storage.TxExecutor(conn)), nil
+}
+
+// lockName fits the lock key into the 64 characters limit of MySQL lock names.
+func lockName(key string) string {
+	if len(key) <= 64 {
+		return key
+	}
+	sum := sha1.Sum([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// release closes the migrate instance, so its dedicated connection is returned to the pool.
+func release(s *migrate.Migrate) {
+	_, _ = s.Close()
+}
+
+// dsn returns MYSQL_DSN or builds the one from host, credentials and database, extends it by the extra params
+// and the TLS config (registered under the instance name) and forces multi statements required by migrations.
+func dsn(name string, cfg Configurator) (string, error) {
+	dsn := cfg.GetMySQLDSN()
+	if dsn == "" {
+		c := mysqldriver.NewConfig()
+		c.User = cfg.GetMySQLUsername()
+		c.Passwd = cfg.GetMySQLPassword()
+		c.Net = "tcp"
+		c.Addr = cfg.GetMySQLHost()
+		if cfg.GetMySQLPort() != "" {
+			c.Addr = net.JoinHostPort(cfg.GetMySQLHost(), cfg.GetMySQLPort())
+		}
+		c.DBName = cfg.GetMySQLDatabase()
+		dsn = c.FormatDSN()
+	}
+
+	params := url.Values{}
+	for key, value := range cfg.GetMySQLParams() {
+		params.Set(key, value)
+	}
+
+	if cfg.IsMySQLTLSEnabled() {
+		tlsCfg, err := storage.NewTLSConfig(
+			cfg.GetMySQLTLSCAFile(),
+			cfg.GetMySQLTLSCertFile(),
+			cfg.GetMySQLTLSKeyFile(),
+			cfg.IsMySQLTLSInsecureSkipVerify(),
+		)
+		if err != nil {
+			return "", err
+		}
+
+		key := "go-migrate-" + name
+		if err = mysqldriver.RegisterTLSConfig(key, tlsCfg); err != nil {
+			return "", err
+		}
+		params.Set("tls", key)
+	}
+
+	if len(params) > 0 {
+		separator := "?"
+		// params follow the database name, the password may contain "?" as well
+		if strings.Contains(dsn[strings.LastIndex(dsn, "/")+1:], "?") {
+			separator = "&"
+		}
+		dsn += separator + params.Encode()
+	}
+
+	c, err := mysqldriver.ParseDSN(dsn)
+	if err != nil {
+		return "", err
+	}
+	c.MultiStatements = true
+
+	return c.FormatDSN(), nil
+}
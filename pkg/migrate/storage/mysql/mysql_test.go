@@ -0,0 +1,64 @@
+package mysql
+
+import (
+	"testing"
+)
+
+func TestDSN(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      *Config
+		expected string
+	}{
+		{
+			name: "credentials and params",
+			cfg: &Config{
+				MySQLHost:     "db.local",
+				MySQLPort:     "3306",
+				MySQLUsername: "app",
+				MySQLPassword: "p@ss:w/rd?",
+				MySQLDatabase: "billing",
+				MySQLParams:   map[string]string{"parseTime": "true", "loc": "Europe/Berlin"},
+			},
+			expected: "app:p@ss:w/rd?@tcp(db.local:3306)/billing?loc=Europe%2FBerlin&multiStatements=true&parseTime=true",
+		},
+		{
+			name: "explicit dsn",
+			cfg: &Config{
+				MySQLHost: "ignored",
+				MySQLDSN:  "app:secret@unix(/var/run/mysqld.sock)/billing?charset=utf8mb4",
+			},
+			expected: "app:secret@unix(/var/run/mysqld.sock)/billing?multiStatements=true&charset=utf8mb4",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := dsn(DriverName, tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual != tt.expected {
+				t.Fatalf("expected %s, got %s", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestDSN_TLS(t *testing.T) {
+	cfg := &Config{
+		MySQLDSN:        "app:secret@tcp(db.local:3306)/billing",
+		MySQLTLSEnabled: true,
+	}
+
+	// the instances sharing MYSQL_DSN get their own TLS configs
+	for _, name := range []string{"billing", "users"} {
+		actual, err := dsn(name, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := "app:secret@tcp(db.local:3306)/billing?multiStatements=true&tls=go-migrate-" + name; actual != expected {
+			t.Fatalf("expected %s, got %s", expected, actual)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/Borislavv/go-migrate/pkg/migrate/storage"
+	"io/fs"
+)
+
+var (
+	ErrFailedLoadMySQLConfig     = errors.New("failed to load MySQL config")
+	ErrFailedCreateInstanceMySQL = errors.New("failed to create MySQL instance")
+)
+
+var registration = storage.Driver[Configurator, *MySQL]{
+	Name:    DriverName,
+	Load:    func(prefix string) (Configurator, error) { return LoadWithPrefix(prefix) },
+	Enabled: Configurator.IsMySQLMigrationsEnabled,
+	Defaults: func(cfg Configurator) Configurator {
+		if c, ok := cfg.(*Config); ok && c != nil {
+			return storage.WithDefaults(c)
+		}
+		return cfg
+	},
+	New:       New,
+	NewNamed:  NewNamed,
+	Rename:    func(m *MySQL, name string) { m.name = name },
+	ErrLoad:   ErrFailedLoadMySQLConfig,
+	ErrCreate: ErrFailedCreateInstanceMySQL,
+}
+
+func init() {
+	storage.Register(DriverName, Make)
+}
+
+// Make loads the instance config from env and creates the storage, it's registered as the storage.Constructor.
+func Make(ctx context.Context, instance storage.Instance) (storage.Storager, error) {
+	return registration.Make(ctx, instance)
+}
+
+// WithConfig replaces the env configured storage by the given config.
+func WithConfig(cfg Configurator, fsys fs.FS) storage.Option {
+	return WithNamedConfig(DriverName, cfg, fsys)
+}
+
+// WithNamedConfig adds the named instance of the storage configured by the given config.
+func WithNamedConfig(name string, cfg Configurator, fsys fs.FS) storage.Option {
+	return registration.WithConfig(name, cfg, fsys)
+}
+
+// WithDB replaces the env configured storage by the one which uses the existing connection pool of the application.
+// The pool must allow enough open connections, see NewWithDB.
+func WithDB(db *sql.DB, cfg Configurator, fsys fs.FS) storage.Option {
+	return WithNamedDB(DriverName, db, cfg, fsys)
+}
+
+// WithNamedDB adds the named instance of the storage which uses the existing connection pool of the application.
+func WithNamedDB(name string, db *sql.DB, cfg Configurator, fsys fs.FS) storage.Option {
+	return registration.With(name, cfg, fsys, func(ctx context.Context, cfg Configurator, fsys fs.FS) (*MySQL, error) {
+		return NewWithDB(ctx, db, cfg, fsys)
+	})
+}
@@ -0,0 +1,53 @@
+package storage
+
+// Option configures the Factory, Filesystems and Instance are options as well.
+type Option interface {
+	apply(f *Factory)
+}
+
+type optionFunc func(f *Factory)
+
+func (fn optionFunc) apply(f *Factory) {
+	fn(f)
+}
+
+func (fs Filesystems) apply(f *Factory) {
+	for name, fsys := range fs {
+		f.filesystems[name] = fsys
+	}
+}
+
+func (i Instance) apply(f *Factory) {
+	f.instances = append(f.instances, i)
+}
+
+// WithStorage adds the instance which is made by the given constructor instead of the registered (env based) one,
+// drivers use it to provide programmatic configuration (see postgres.WithConfig and so on).
+// An instance named after its driver replaces the default one.
+func WithStorage(instance Instance, constructor Constructor) Option {
+	return optionFunc(func(f *Factory) {
+		instance.constructor = constructor
+		f.instances = append(f.instances, instance)
+	})
+}
+
+// WithoutEnv disables the default (env configured) instances of the registered drivers,
+// so only the explicitly given instances are made.
+func WithoutEnv() Option {
+	return optionFunc(func(f *Factory) {
+		f.withoutEnv = true
+	})
+}
+
+// Only keeps the instances of the given names (default instances are named after their drivers), the others
+// are not made at all, so they never connect. All instances are kept when no names are given.
+func Only(names ...string) Option {
+	return optionFunc(func(f *Factory) {
+		for _, name := range names {
+			if f.only == nil {
+				f.only = make(map[string]struct{}, len(names))
+			}
+			f.only[name] = struct{}{}
+		}
+	})
+}
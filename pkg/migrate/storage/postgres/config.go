@@ -1,6 +1,9 @@
 package postgres
 
-import "github.com/kelseyhightower/envconfig"
+import (
+	"github.com/Borislavv/go-migrate/pkg/migrate/storage"
+	"time"
+)
 
 type Configurator interface {
 	IsPostgresMigrationsEnabled() bool
@@ -9,25 +12,53 @@ type Configurator interface {
 	GetPostgresDatabase() string
 	GetPostgresHost() string
 	GetPostgresPort() string
+	GetPostgresDSN() string
+	GetPostgresSSLMode() string
+	GetPostgresSSLRootCert() string
+	GetPostgresSSLCert() string
+	GetPostgresSSLKey() string
+	GetPostgresParams() map[string]string
 	GetPostgresMigrationsTable() string
+	GetPostgresMigrationsHistoryTable() string
+	GetPostgresConnectTimeout() time.Duration
+	GetPostgresConnectRetries() int
+	GetPostgresMigrationsDir() string
+	GetPostgresMigrationsTimeout() time.Duration
 }
 
 type Config struct {
-	PostgresMigrationsEnabled bool   `envconfig:"POSTGRES_MIGRATIONS_ENABLED" default:"false"`
-	PostgresHost              string `envconfig:"POSTGRES_HOST"`
-	PostgresPort              string `envconfig:"POSTGRES_PORT"`
-	PostgresUsername          string `envconfig:"POSTGRES_LOGIN"`
-	PostgresPassword          string `envconfig:"POSTGRES_PASSWORD"`
-	PostgresDatabase          string `envconfig:"POSTGRES_DATABASE"`
-	PostgresMigrationsTable   string `envconfig:"POSTGRES_MIGRATIONS_TABLE" default:"migration_versions"`
+	PostgresMigrationsEnabled      bool              `envconfig:"POSTGRES_MIGRATIONS_ENABLED" default:"false"`
+	PostgresHost                   string            `envconfig:"POSTGRES_HOST"`
+	PostgresPort                   string            `envconfig:"POSTGRES_PORT"`
+	PostgresUsername               string            `envconfig:"POSTGRES_LOGIN"`
+	PostgresPassword               string            `envconfig:"POSTGRES_PASSWORD"`
+	PostgresDatabase               string            `envconfig:"POSTGRES_DATABASE"`
+	PostgresDSN                    string            `envconfig:"POSTGRES_DSN"`
+	PostgresSSLMode                string            `envconfig:"POSTGRES_SSL_MODE"`
+	PostgresSSLRootCert            string            `envconfig:"POSTGRES_SSL_ROOT_CERT"`
+	PostgresSSLCert                string            `envconfig:"POSTGRES_SSL_CERT"`
+	PostgresSSLKey                 string            `envconfig:"POSTGRES_SSL_KEY"`
+	PostgresParams                 map[string]string `envconfig:"POSTGRES_PARAMS"`
+	PostgresMigrationsTable        string            `envconfig:"POSTGRES_MIGRATIONS_TABLE" default:"migration_versions"`
+	PostgresMigrationsHistoryTable string            `envconfig:"POSTGRES_MIGRATIONS_HISTORY_TABLE" default:"migration_history"`
+	PostgresConnectTimeout         time.Duration     `envconfig:"POSTGRES_CONNECT_TIMEOUT" default:"0"`
+	PostgresConnectRetries         int               `envconfig:"POSTGRES_CONNECT_RETRIES" default:"0"`
+	PostgresMigrationsDir          string            `envconfig:"POSTGRES_MIGRATIONS_DIR" default:"migrations"`
+	PostgresMigrationsTimeout      time.Duration     `envconfig:"POSTGRES_MIGRATIONS_TIMEOUT" default:"0"`
+}
+
+// DefaultConfig returns the config with the same defaults as the env one.
+func DefaultConfig() *Config {
+	return storage.SetDefaults(new(Config))
 }
 
 func Load() (*Config, error) {
-	cfg := new(Config)
-	if err := envconfig.Process("", cfg); err != nil {
-		return nil, err
-	}
-	return cfg, nil
+	return LoadWithPrefix("")
+}
+
+// LoadWithPrefix loads the config of a named storage instance, e.g. BILLING_POSTGRES_HOST for the "BILLING" prefix.
+func LoadWithPrefix(prefix string) (*Config, error) {
+	return storage.LoadEnv[Config](prefix)
 }
 
 func (c *Config) IsPostgresMigrationsEnabled() bool {
@@ -54,6 +85,50 @@ func (c *Config) GetPostgresDatabase() string {
 	return c.PostgresDatabase
 }
 
+func (c *Config) GetPostgresDSN() string {
+	return c.PostgresDSN
+}
+
+func (c *Config) GetPostgresSSLMode() string {
+	return c.PostgresSSLMode
+}
+
+func (c *Config) GetPostgresSSLRootCert() string {
+	return c.PostgresSSLRootCert
+}
+
+func (c *Config) GetPostgresSSLCert() string {
+	return c.PostgresSSLCert
+}
+
+func (c *Config) GetPostgresSSLKey() string {
+	return c.PostgresSSLKey
+}
+
+func (c *Config) GetPostgresParams() map[string]string {
+	return c.PostgresParams
+}
+
 func (c *Config) GetPostgresMigrationsTable() string {
 	return c.PostgresMigrationsTable
 }
+
+func (c *Config) GetPostgresMigrationsHistoryTable() string {
+	return c.PostgresMigrationsHistoryTable
+}
+
+func (c *Config) GetPostgresConnectTimeout() time.Duration {
+	return c.PostgresConnectTimeout
+}
+
+func (c *Config) GetPostgresConnectRetries() int {
+	return c.PostgresConnectRetries
+}
+
+func (c *Config) GetPostgresMigrationsDir() string {
+	return c.PostgresMigrationsDir
+}
+
+func (c *Config) GetPostgresMigrationsTimeout() time.Duration {
+	return c.PostgresMigrationsTimeout
+}
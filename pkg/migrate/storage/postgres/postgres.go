@@ -3,36 +3,36 @@ package postgres
 import (
 	"context"
 	"database/sql"
-	"embed"
 	"errors"
 	"fmt"
+	"github.com/Borislavv/go-migrate/pkg/migrate/source"
+	"github.com/Borislavv/go-migrate/pkg/migrate/storage"
 	"github.com/Borislavv/migrate/v4"
 	"github.com/Borislavv/migrate/v4/database/postgres"
-	"os"
-	"path/filepath"
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"hash/fnv"
+	"io/fs"
+	"net"
+	"net/url"
 )
 
-const DriverName = "postgres"
+const (
+	DriverName = "postgres"
+	// MigrationsExt is the extension of the migration files.
+	MigrationsExt = "sql"
+)
 
 type Postgres struct {
-	ctx context.Context
-	db  *sql.DB
-	cfg Configurator
-	fs  embed.FS
+	name    string
+	db      *sql.DB
+	cfg     Configurator
+	fs      fs.FS
+	history storage.History
 }
 
-func New(ctx context.Context, cfg Configurator, fs embed.FS) (*Postgres, error) {
-	dsn := fmt.Sprintf(
-		"%s://%s:%s@%s:%s/%s",
-		DriverName,
-		cfg.GetPostgresUsername(),
-		cfg.GetPostgresPassword(),
-		cfg.GetPostgresHost(),
-		cfg.GetPostgresPort(),
-		cfg.GetPostgresDatabase(),
-	)
-
-	db, err := sql.Open(DriverName, dsn)
+func New(ctx context.Context, cfg Configurator, fsys fs.FS) (*Postgres, error) {
+	db, err := sql.Open(DriverName, dsn(cfg))
 	if err != nil {
 		return nil, err
 	}
@@ -42,101 +42,320 @@ func New(ctx context.Context, cfg Configurator, fs embed.FS) (*Postgres, error)
 		_ = db.Close()
 	}()
 
-	if err = db.Ping(); err != nil {
+	return NewWithDB(ctx, db, cfg, fsys)
+}
+
+// NewWithDB makes the storage on top of the existing connection pool, which is owned (and closed) by the caller.
+// The pool must allow at least storage.MinPoolConns open connections (storage.MinLockPoolConns with the distributed
+// lock enabled), a smaller one is rejected by storage.ErrPoolTooSmall.
+func NewWithDB(ctx context.Context, db *sql.DB, cfg Configurator, fsys fs.FS) (*Postgres, error) {
+	if err := storage.CheckPool(db, storage.MinPoolConns); err != nil {
+		return nil, err
+	}
+	if err := storage.Connect(ctx, cfg.GetPostgresConnectRetries(), cfg.GetPostgresConnectTimeout(), db.PingContext); err != nil {
 		return nil, err
 	}
 
-	return &Postgres{ctx: ctx, db: db, cfg: cfg, fs: fs}, nil
+	var h storage.History
+	if table := cfg.GetPostgresMigrationsHistoryTable(); storage.IsHistoryEnabled(table) {
+		h = storage.NewSQLHistory(db, table, storage.SQLHistoryDDL("TIMESTAMP(6)"), true)
+	}
+
+	return &Postgres{
+		name:    DriverName,
+		db:      db,
+		cfg:     cfg,
+		fs:      fsys,
+		history: h,
+	}, nil
 }
 
 func (m *Postgres) Name() string {
-	return DriverName
+	return m.name
 }
 
-func (m *Postgres) Up() error {
-	s, err := m.migrate()
+func (m *Postgres) Up(ctx context.Context) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	s, err := m.migrate(ctx)
 	if err != nil {
 		return err
 	}
+	defer release(s)
+
+	return storage.Run(ctx, s, s.Up)
+}
+
+func (m *Postgres) Down(ctx context.Context) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
 
-	if err = s.Up(); err != nil {
+	s, err := m.migrate(ctx)
+	if err != nil {
 		return err
 	}
+	defer release(s)
 
-	return nil
+	return storage.Run(ctx, s, s.Down)
 }
 
-func (m *Postgres) Down() error {
-	s, err := m.migrate()
+func (m *Postgres) Migrate(ctx context.Context, version uint) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	s, err := m.migrate(ctx)
 	if err != nil {
 		return err
 	}
+	defer release(s)
+
+	return storage.Run(ctx, s, func() error { return s.Migrate(version) })
+}
+
+func (m *Postgres) Steps(ctx context.Context, n int) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
 
-	if err = s.Down(); err != nil {
+	s, err := m.migrate(ctx)
+	if err != nil {
 		return err
 	}
+	defer release(s)
 
-	return nil
+	return storage.Run(ctx, s, func() error { return s.Steps(n) })
 }
 
-func (m *Postgres) Force(n int) error {
-	s, err := m.migrate()
+func (m *Postgres) Force(ctx context.Context, n int) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	s, err := m.migrate(ctx)
 	if err != nil {
 		return err
 	}
+	defer release(s)
 
 	if err = s.Force(n); err != nil {
 		return err
 	}
 
-	return nil
+	return ctx.Err()
 }
 
-func (m *Postgres) Version() (version uint, dirty bool, err error) {
-	s, err := m.migrate()
+func (m *Postgres) Version(ctx context.Context) (version uint, dirty bool, err error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	s, err := m.migrate(ctx)
 	if err != nil {
 		return 0, true, err
 	}
+	defer release(s)
 
 	return s.Version()
 }
 
-func (m *Postgres) migrate() (*migrate.Migrate, error) {
-	if m.db == nil {
-		return nil, errors.New("the underlying database pointer is not initialized, you need to call the 'New' method first")
+// Migrations returns all migrations available in the migrations fs and the registered Go ones ordered by version.
+func (m *Postgres) Migrations() ([]source.Migration, error) {
+	migrations, err := source.List(m.fs, m.cfg.GetPostgresMigrationsDir())
+	if err != nil {
+		return nil, err
 	}
 
-	d, err := postgres.WithInstance(m.ctx, m.db, &postgres.Config{
-		DatabaseName:    m.cfg.GetPostgresDatabase(),
-		MigrationsTable: m.cfg.GetPostgresMigrationsTable(),
-	})
+	return storage.WithGoMigrations(m.name, migrations)
+}
+
+// ReadMigration returns the content of the migration file by its path in the migrations fs (or of the Go migration).
+func (m *Postgres) ReadMigration(path string) ([]byte, error) {
+	if content, ok := storage.ReadGoMigration(m.name, path); ok {
+		return content, nil
+	}
+	return fs.ReadFile(m.fs, path)
+}
+
+// History returns the executed migrations recorded into POSTGRES_MIGRATIONS_HISTORY_TABLE,
+// the history is disabled when it's empty or storage.HistoryDisabled, see storage.Historian.
+func (m *Postgres) History(ctx context.Context) ([]storage.HistoryEntry, error) {
+	if m.history == nil {
+		return nil, storage.ErrHistoryIsNotEnabled
+	}
+
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	return m.history.Entries(ctx)
+}
+
+// Apply executes the up file of the migration without moving the version, see storage.Applier.
+func (m *Postgres) Apply(ctx context.Context, migration source.Migration) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	content, err := m.ReadMigration(migration.Up)
+	if err != nil {
+		return err
+	}
+
+	d, err := m.driver(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = d.Close() }()
+
+	return storage.Apply(ctx, d, m.history, migration, content)
+}
+
+// Repair executes the down file of the failed migration (if requested) and forces the clean version,
+// see storage.Repairer.
+func (m *Postgres) Repair(ctx context.Context, failed source.Migration, version int, down bool) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	var content []byte
+	if down && failed.Down != "" {
+		var err error
+		if content, err = m.ReadMigration(failed.Down); err != nil {
+			return err
+		}
+	}
+
+	d, err := m.driver(ctx)
 	if err != nil {
+		return err
+	}
+	defer func() { _ = d.Close() }()
+
+	return storage.Repair(ctx, d, m.history, failed, content, version)
+}
+
+// Lock acquires the session level advisory lock on a dedicated connection, see storage.Locker.
+func (m *Postgres) Lock(ctx context.Context, key string) (unlock func() error, err error) {
+	if err = storage.CheckPool(m.db, storage.MinLockPoolConns); err != nil {
 		return nil, err
 	}
 
-	rootDir, err := os.Getwd()
+	conn, err := m.db.Conn(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get current working directory: %w", err)
+		return nil, err
 	}
 
-	destDir := filepath.Join(rootDir, "tmp", DriverName)
-	if err = os.RemoveAll(destDir); err != nil {
-		return nil, fmt.Errorf("could not clear temporary MongoDB migrations directory: %w", err)
+	id := lockID(key)
+	if err = storage.PollLock(ctx, func(ctx context.Context) (acquired bool, err error) {
+		err = conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", id).Scan(&acquired)
+		return acquired, err
+	}); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	return func() error {
+		defer func() { _ = conn.Close() }()
+		_, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", id)
+		return err
+	}, nil
+}
+
+// withTimeout limits ctx by the configured migrations timeout (if any).
+func (m *Postgres) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return storage.WithTimeout(ctx, m.cfg.GetPostgresMigrationsTimeout())
+}
+
+func (m *Postgres) migrate(ctx context.Context) (*migrate.Migrate, error) {
+	migrations, err := m.Migrations()
+	if err != nil {
+		return nil, err
 	}
 
-	if err = os.MkdirAll(destDir, 0777); err != nil {
-		return nil, fmt.Errorf("could not create PostgreSQL migrations directory: %w", err)
+	d, err := m.driver(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	if err = os.CopyFS(destDir, m.fs); err != nil {
-		return nil, fmt.Errorf("could not copy PostgreSQL migrations fs: %w", err)
+	src, err := iofs.New(m.fs, m.cfg.GetPostgresMigrationsDir())
+	if err != nil {
+		_ = d.Close()
+		return nil, fmt.Errorf("could not open PostgreSQL migrations fs: %w", err)
 	}
 
-	migrationsDir := filepath.Join(destDir, "migrations")
-	s, err := migrate.NewWithDatabaseInstance("file://"+migrationsDir, DriverName, d)
+	s, err := migrate.NewWithInstance("iofs", storage.GoSource(src, m.name, migrations), DriverName, storage.Record(ctx, d, m.history, migrations))
 	if err != nil {
+		_ = d.Close()
 		return nil, err
 	}
 
 	return s, nil
 }
+
+// driver makes the migrate database driver, it must be closed to return the dedicated connection to the pool.
+func (m *Postgres) driver(ctx context.Context) (database.Driver, error) {
+	if m.db == nil {
+		return nil, errors.New("the underlying database pointer is not initialized, you need to call the 'New' method first")
+	}
+
+	// a dedicated connection is returned to the pool once the driver is closed, the pool itself is left open
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := postgres.WithConnection(ctx, conn, &postgres.Config{
+		DatabaseName:    m.cfg.GetPostgresDatabase(),
+		MigrationsTable: m.cfg.GetPostgresMigrationsTable(),
+	})
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	return storage.GoDriver(ctx, d, m.name, storage.TxExecutor(conn)), nil
+}
+
+// lockID maps the lock key to the advisory lock id.
+func lockID(key string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	return int64(h.Sum64())
+}
+
+// release closes the migrate instance, so its dedicated connection is returned to the pool.
+func release(s *migrate.Migrate) {
+	_, _ = s.Close()
+}
+
+// dsn returns POSTGRES_DSN as is or builds the connection URL with escaped credentials, ssl settings and extra params.
+func dsn(cfg Configurator) string {
+	if cfg.GetPostgresDSN() != "" {
+		return cfg.GetPostgresDSN()
+	}
+
+	query := url.Values{}
+	for key, value := range cfg.GetPostgresParams() {
+		query.Set(key, value)
+	}
+	for key, value := range map[string]string{
+		"sslmode":     cfg.GetPostgresSSLMode(),
+		"sslrootcert": cfg.GetPostgresSSLRootCert(),
+		"sslcert":     cfg.GetPostgresSSLCert(),
+		"sslkey":      cfg.GetPostgresSSLKey(),
+	} {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+
+	u := &url.URL{
+		Scheme:   DriverName,
+		Host:     cfg.GetPostgresHost(),
+		Path:     "/" + cfg.GetPostgresDatabase(),
+		RawQuery: query.Encode(),
+	}
+	if cfg.GetPostgresPort() != "" {
+		u.Host = net.JoinHostPort(cfg.GetPostgresHost(), cfg.GetPostgresPort())
+	}
+	if cfg.GetPostgresUsername() != "" {
+		u.User = url.UserPassword(cfg.GetPostgresUsername(), cfg.GetPostgresPassword())
+	}
+
+	return u.String()
+}
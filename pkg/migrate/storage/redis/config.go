@@ -0,0 +1,104 @@
+package redis
+
+import (
+	"github.com/Borislavv/go-migrate/pkg/migrate/storage"
+	"time"
+)
+
+type Configurator interface {
+	IsRedisMigrationsEnabled() bool
+	GetRedisHost() string
+	GetRedisPort() string
+	GetRedisURL() string
+	GetRedisUsername() string
+	GetRedisPassword() string
+	GetRedisDatabase() int
+	GetRedisMigrationsKey() string
+	GetRedisMigrationsHistoryKey() string
+	GetRedisConnectTimeout() time.Duration
+	GetRedisConnectRetries() int
+	GetRedisMigrationsDir() string
+	GetRedisMigrationsTimeout() time.Duration
+}
+
+type Config struct {
+	RedisMigrationsEnabled    bool          `envconfig:"REDIS_MIGRATIONS_ENABLED" default:"false"`
+	RedisHost                 string        `envconfig:"REDIS_HOST"`
+	RedisPort                 string        `envconfig:"REDIS_PORT" default:"6379"`
+	RedisURL                  string        `envconfig:"REDIS_URL"`
+	RedisUsername             string        `envconfig:"REDIS_LOGIN"`
+	RedisPassword             string        `envconfig:"REDIS_PASSWORD"`
+	RedisDatabase             int           `envconfig:"REDIS_DATABASE" default:"0"`
+	RedisMigrationsKey        string        `envconfig:"REDIS_MIGRATIONS_KEY" default:"migration_versions"`
+	RedisMigrationsHistoryKey string        `envconfig:"REDIS_MIGRATIONS_HISTORY_KEY" default:"migration_history"`
+	RedisConnectTimeout       time.Duration `envconfig:"REDIS_CONNECT_TIMEOUT" default:"0"`
+	RedisConnectRetries       int           `envconfig:"REDIS_CONNECT_RETRIES" default:"0"`
+	RedisMigrationsDir        string        `envconfig:"REDIS_MIGRATIONS_DIR" default:"migrations"`
+	RedisMigrationsTimeout    time.Duration `envconfig:"REDIS_MIGRATIONS_TIMEOUT" default:"0"`
+}
+
+// DefaultConfig returns the config with the same defaults as the env one.
+func DefaultConfig() *Config {
+	return storage.SetDefaults(new(Config))
+}
+
+func Load() (*Config, error) {
+	return LoadWithPrefix("")
+}
+
+// LoadWithPrefix loads the config of a named storage instance, e.g. BILLING_REDIS_HOST for the "BILLING" prefix.
+func LoadWithPrefix(prefix string) (*Config, error) {
+	return storage.LoadEnv[Config](prefix)
+}
+
+func (c *Config) IsRedisMigrationsEnabled() bool {
+	return c.RedisMigrationsEnabled
+}
+
+func (c *Config) GetRedisHost() string {
+	return c.RedisHost
+}
+
+func (c *Config) GetRedisPort() string {
+	return c.RedisPort
+}
+
+func (c *Config) GetRedisURL() string {
+	return c.RedisURL
+}
+
+func (c *Config) GetRedisUsername() string {
+	return c.RedisUsername
+}
+
+func (c *Config) GetRedisPassword() string {
+	return c.RedisPassword
+}
+
+func (c *Config) GetRedisDatabase() int {
+	return c.RedisDatabase
+}
+
+func (c *Config) GetRedisMigrationsKey() string {
+	return c.RedisMigrationsKey
+}
+
+func (c *Config) GetRedisMigrationsHistoryKey() string {
+	return c.RedisMigrationsHistoryKey
+}
+
+func (c *Config) GetRedisConnectTimeout() time.Duration {
+	return c.RedisConnectTimeout
+}
+
+func (c *Config) GetRedisConnectRetries() int {
+	return c.RedisConnectRetries
+}
+
+func (c *Config) GetRedisMigrationsDir() string {
+	return c.RedisMigrationsDir
+}
+
+func (c *Config) GetRedisMigrationsTimeout() time.Duration {
+	return c.RedisMigrationsTimeout
+}
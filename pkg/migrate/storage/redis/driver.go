@@ -0,0 +1,150 @@
+package redis
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Borislavv/go-migrate/pkg/migrate/storage"
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/kballard/go-shellquote"
+	"github.com/redis/go-redis/v9"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LuaHeader marks a migration file which must be executed as a single Lua script (EVAL with no keys),
+// other files are executed line by line as Redis commands (empty lines and lines starting with # are skipped).
+const LuaHeader = "#!lua"
+
+const lockTTL = time.Minute
+
+var ErrOpenIsNotSupported = errors.New("redis migrations driver can be created only by WithInstance")
+
+// driver implements the migrate database.Driver storing the version and dirty flag in a hash.
+type driver struct {
+	ctx     context.Context
+	client  redis.UniversalClient
+	key     string
+	lockKey string
+	// owner is the value of the held lock key, stop ends its extension, both are reset by Unlock.
+	owner string
+	stop  func()
+}
+
+func withInstance(ctx context.Context, client redis.UniversalClient, key string) (database.Driver, error) {
+	if err := client.Ping(ctx).Err(); err != nil {
+		return nil, err
+	}
+	return &driver{ctx: ctx, client: client, key: key, lockKey: key + ":lock"}, nil
+}
+
+func (d *driver) Open(_ string) (database.Driver, error) {
+	return nil, ErrOpenIsNotSupported
+}
+
+// Close does nothing as the client is owned by the storage.
+func (d *driver) Close() error {
+	return nil
+}
+
+// Lock takes the key by a random owner value and extends it while it's held, so a long migration doesn't lose it
+// and an expired lock taken over by another process isn't released by this one.
+func (d *driver) Lock() error {
+	owner := storage.LockOwner()
+	ok, err := d.client.SetNX(d.ctx, d.lockKey, owner, lockTTL).Result()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return database.ErrLocked
+	}
+
+	d.owner = owner
+	d.stop = storage.Heartbeat(lockTTL/3, func(ctx context.Context) error {
+		return extendScript.Run(ctx, d.client, []string{d.lockKey}, owner, lockTTL.Milliseconds()).Err()
+	})
+	return nil
+}
+
+// Unlock deletes the key only while it's held by the owner.
+func (d *driver) Unlock() error {
+	if d.stop == nil {
+		return nil
+	}
+	d.stop()
+
+	owner := d.owner
+	d.owner, d.stop = "", nil
+	return releaseScript.Run(context.Background(), d.client, []string{d.lockKey}, owner).Err()
+}
+
+func (d *driver) Run(migration io.Reader) error {
+	content, err := io.ReadAll(migration)
+	if err != nil {
+		return err
+	}
+
+	if script, ok := bytes.CutPrefix(bytes.TrimLeft(content, " \t\r\n"), []byte(LuaHeader)); ok {
+		if err = d.client.Eval(d.ctx, string(script), nil).Err(); err != nil && !errors.Is(err, redis.Nil) {
+			return &database.Error{OrigErr: err, Query: script}
+		}
+		return nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for line := 1; scanner.Scan(); line++ {
+		command := strings.TrimSpace(scanner.Text())
+		if command == "" || strings.HasPrefix(command, "#") {
+			continue
+		}
+
+		words, err := shellquote.Split(command)
+		if err != nil {
+			return &database.Error{OrigErr: err, Line: uint(line), Query: []byte(command)}
+		}
+
+		args := make([]any, 0, len(words))
+		for _, word := range words {
+			args = append(args, word)
+		}
+
+		if err = d.client.Do(d.ctx, args...).Err(); err != nil && !errors.Is(err, redis.Nil) {
+			return &database.Error{OrigErr: err, Line: uint(line), Query: []byte(command)}
+		}
+	}
+
+	return scanner.Err()
+}
+
+func (d *driver) SetVersion(version int, dirty bool) error {
+	if version == database.NilVersion && !dirty {
+		return d.client.Del(d.ctx, d.key).Err()
+	}
+	return d.client.HSet(d.ctx, d.key, "version", version, "dirty", dirty).Err()
+}
+
+func (d *driver) Version() (version int, dirty bool, err error) {
+	values, err := d.client.HGetAll(d.ctx, d.key).Result()
+	if err != nil {
+		return database.NilVersion, false, err
+	}
+	if len(values) == 0 {
+		return database.NilVersion, false, nil
+	}
+
+	if version, err = strconv.Atoi(values["version"]); err != nil {
+		return database.NilVersion, false, fmt.Errorf("invalid version in %s: %w", d.key, err)
+	}
+	dirty, _ = strconv.ParseBool(values["dirty"])
+
+	return version, dirty, nil
+}
+
+// Drop removes only the version and lock keys, the data is left untouched as the keyspace may be shared.
+func (d *driver) Drop() error {
+	return d.client.Del(d.ctx, d.key, d.lockKey).Err()
+}
@@ -0,0 +1,39 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/Borislavv/go-migrate/pkg/migrate/storage"
+	"github.com/redis/go-redis/v9"
+)
+
+// history keeps the history entries JSON encoded in the list, see storage.History.
+type history struct {
+	client redis.UniversalClient
+	key    string
+}
+
+func (h *history) Append(ctx context.Context, entry storage.HistoryEntry) error {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return h.client.RPush(ctx, h.key, b).Err()
+}
+
+func (h *history) Entries(ctx context.Context) ([]storage.HistoryEntry, error) {
+	values, err := h.client.LRange(ctx, h.key, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]storage.HistoryEntry, 0, len(values))
+	for _, value := range values {
+		var entry storage.HistoryEntry
+		if err = json.Unmarshal([]byte(value), &entry); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
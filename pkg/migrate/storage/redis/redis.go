@@ -0,0 +1,302 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Borislavv/go-migrate/pkg/migrate/source"
+	"github.com/Borislavv/go-migrate/pkg/migrate/storage"
+	"github.com/Borislavv/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/redis/go-redis/v9"
+	"io/fs"
+	"net"
+)
+
+const (
+	DriverName = "redis"
+	// MigrationsExt is the extension of the migration files.
+	MigrationsExt = "redis"
+)
+
+var (
+	// extendScript prolongs the lock only while it's held by the owner.
+	extendScript = redis.NewScript(`if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("PEXPIRE", KEYS[1], ARGV[2]) end return 0`)
+	// releaseScript deletes the lock only while it's held by the owner.
+	releaseScript = redis.NewScript(`if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("DEL", KEYS[1]) end return 0`)
+)
+
+type Redis struct {
+	name    string
+	client  redis.UniversalClient
+	cfg     Configurator
+	fs      fs.FS
+	history storage.History
+}
+
+func New(ctx context.Context, cfg Configurator, fsys fs.FS) (*Redis, error) {
+	opts, err := options(cfg)
+	if err != nil {
+		return nil, err
+	}
+	client := redis.NewClient(opts)
+
+	go func() {
+		<-ctx.Done()
+		_ = client.Close()
+	}()
+
+	return NewWithClient(ctx, client, cfg, fsys)
+}
+
+// NewWithClient makes the storage on top of the existing client, which is owned (and closed) by the caller.
+func NewWithClient(ctx context.Context, client redis.UniversalClient, cfg Configurator, fsys fs.FS) (*Redis, error) {
+	if err := storage.Connect(ctx, cfg.GetRedisConnectRetries(), cfg.GetRedisConnectTimeout(), func(ctx context.Context) error {
+		return client.Ping(ctx).Err()
+	}); err != nil {
+		return nil, err
+	}
+
+	var h storage.History
+	if key := cfg.GetRedisMigrationsHistoryKey(); storage.IsHistoryEnabled(key) {
+		h = &history{client: client, key: key}
+	}
+
+	return &Redis{
+		name:    DriverName,
+		client:  client,
+		cfg:     cfg,
+		fs:      fsys,
+		history: h,
+	}, nil
+}
+
+func (m *Redis) Name() string {
+	return m.name
+}
+
+func (m *Redis) Up(ctx context.Context) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	s, err := m.migrate(ctx)
+	if err != nil {
+		return err
+	}
+
+	return storage.Run(ctx, s, s.Up)
+}
+
+func (m *Redis) Down(ctx context.Context) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	s, err := m.migrate(ctx)
+	if err != nil {
+		return err
+	}
+
+	return storage.Run(ctx, s, s.Down)
+}
+
+func (m *Redis) Migrate(ctx context.Context, version uint) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	s, err := m.migrate(ctx)
+	if err != nil {
+		return err
+	}
+
+	return storage.Run(ctx, s, func() error { return s.Migrate(version) })
+}
+
+func (m *Redis) Steps(ctx context.Context, n int) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	s, err := m.migrate(ctx)
+	if err != nil {
+		return err
+	}
+
+	return storage.Run(ctx, s, func() error { return s.Steps(n) })
+}
+
+func (m *Redis) Force(ctx context.Context, n int) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	s, err := m.migrate(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err = s.Force(n); err != nil {
+		return err
+	}
+
+	return ctx.Err()
+}
+
+func (m *Redis) Version(ctx context.Context) (version uint, dirty bool, err error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	s, err := m.migrate(ctx)
+	if err != nil {
+		return 0, true, err
+	}
+
+	return s.Version()
+}
+
+// Migrations returns all migrations available in the migrations fs and the registered Go ones ordered by version.
+func (m *Redis) Migrations() ([]source.Migration, error) {
+	migrations, err := source.List(m.fs, m.cfg.GetRedisMigrationsDir())
+	if err != nil {
+		return nil, err
+	}
+
+	return storage.WithGoMigrations(m.name, migrations)
+}
+
+// ReadMigration returns the content of the migration file by its path in the migrations fs (or of the Go migration).
+func (m *Redis) ReadMigration(path string) ([]byte, error) {
+	if content, ok := storage.ReadGoMigration(m.name, path); ok {
+		return content, nil
+	}
+	return fs.ReadFile(m.fs, path)
+}
+
+// History returns the executed migrations recorded into REDIS_MIGRATIONS_HISTORY_KEY,
+// the history is disabled when it's empty or storage.HistoryDisabled, see storage.Historian.
+func (m *Redis) History(ctx context.Context) ([]storage.HistoryEntry, error) {
+	if m.history == nil {
+		return nil, storage.ErrHistoryIsNotEnabled
+	}
+
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	return m.history.Entries(ctx)
+}
+
+// Apply executes the up file of the migration without moving the version, see storage.Applier.
+func (m *Redis) Apply(ctx context.Context, migration source.Migration) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	content, err := m.ReadMigration(migration.Up)
+	if err != nil {
+		return err
+	}
+
+	d, err := m.driver(ctx)
+	if err != nil {
+		return err
+	}
+
+	return storage.Apply(ctx, d, m.history, migration, content)
+}
+
+// Repair executes the down file of the failed migration (if requested) and forces the clean version,
+// see storage.Repairer.
+func (m *Redis) Repair(ctx context.Context, failed source.Migration, version int, down bool) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	var content []byte
+	if down && failed.Down != "" {
+		var err error
+		if content, err = m.ReadMigration(failed.Down); err != nil {
+			return err
+		}
+	}
+
+	d, err := m.driver(ctx)
+	if err != nil {
+		return err
+	}
+
+	return storage.Repair(ctx, d, m.history, failed, content, version)
+}
+
+// Lock acquires the key (namespaced by REDIS_MIGRATIONS_KEY) which expires unless it's extended by the holder,
+// see storage.Locker.
+func (m *Redis) Lock(ctx context.Context, key string) (unlock func() error, err error) {
+	key = m.cfg.GetRedisMigrationsKey() + ":" + key
+	owner := storage.LockOwner()
+
+	if err = storage.PollLock(ctx, func(ctx context.Context) (bool, error) {
+		return m.client.SetNX(ctx, key, owner, lockTTL).Result()
+	}); err != nil {
+		return nil, err
+	}
+
+	stop := storage.Heartbeat(lockTTL/3, func(ctx context.Context) error {
+		return extendScript.Run(ctx, m.client, []string{key}, owner, lockTTL.Milliseconds()).Err()
+	})
+
+	return func() error {
+		stop()
+		return releaseScript.Run(context.Background(), m.client, []string{key}, owner).Err()
+	}, nil
+}
+
+// withTimeout limits ctx by the configured migrations timeout (if any).
+func (m *Redis) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return storage.WithTimeout(ctx, m.cfg.GetRedisMigrationsTimeout())
+}
+
+func (m *Redis) migrate(ctx context.Context) (*migrate.Migrate, error) {
+	migrations, err := m.Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := m.driver(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	src, err := iofs.New(m.fs, m.cfg.GetRedisMigrationsDir())
+	if err != nil {
+		return nil, fmt.Errorf("could not open Redis migrations fs: %w", err)
+	}
+
+	s, err := migrate.NewWithInstance("iofs", storage.GoSource(src, m.name, migrations), DriverName, storage.Record(ctx, d, m.history, migrations))
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// driver makes the migrate database driver, closing it is a no-op as the client is owned by the storage.
+func (m *Redis) driver(ctx context.Context) (database.Driver, error) {
+	if m.client == nil {
+		return nil, errors.New("the underlying database pointer is not initialized, you need to call the 'New' method first")
+	}
+
+	d, err := withInstance(ctx, m.client, m.cfg.GetRedisMigrationsKey())
+	if err != nil {
+		return nil, err
+	}
+
+	return storage.GoDriver(ctx, d, m.name, storage.HandleExecutor(m.client)), nil
+}
+
+// options parses REDIS_URL (redis:// or rediss:// for TLS) or builds the client options from the host and credentials.
+func options(cfg Configurator) (*redis.Options, error) {
+	if cfg.GetRedisURL() != "" {
+		return redis.ParseURL(cfg.GetRedisURL())
+	}
+	return &redis.Options{
+		Addr:     net.JoinHostPort(cfg.GetRedisHost(), cfg.GetRedisPort()),
+		Username: cfg.GetRedisUsername(),
+		Password: cfg.GetRedisPassword(),
+		DB:       cfg.GetRedisDatabase(),
+	}, nil
+}
@@ -0,0 +1,190 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"github.com/Borislavv/migrate/v4"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/redis/go-redis/v9"
+	"net"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestRedis(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	srv := miniredis.RunT(t)
+
+	fsys := fstest.MapFS{
+		"migrations/1_seed_settings.up.redis":   {Data: []byte("# default settings\nHSET settings theme \"dark mode\" lang en\n\nSADD features search")},
+		"migrations/1_seed_settings.down.redis": {Data: []byte("DEL settings features")},
+		"migrations/2_seed_counter.up.redis":    {Data: []byte("#!lua\nredis.call('SET', 'counter', 10)\nredis.call('INCRBY', 'counter', 5)")},
+		"migrations/2_seed_counter.down.redis":  {Data: []byte("DEL counter")},
+	}
+
+	s, err := New(ctx, &Config{
+		RedisHost:          srv.Host(),
+		RedisPort:          srv.Port(),
+		RedisMigrationsKey: "migration_versions",
+		RedisMigrationsDir: "migrations",
+	}, fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err = s.Version(ctx); !errors.Is(err, migrate.ErrNilVersion) {
+		t.Fatalf("expected %v, got %v", migrate.ErrNilVersion, err)
+	}
+
+	if err = s.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if version, dirty, err := s.Version(ctx); err != nil || version != 2 || dirty {
+		t.Fatalf("unexpected state: version=%d, dirty=%t, err=%v", version, dirty, err)
+	}
+	if theme := srv.HGet("settings", "theme"); theme != "dark mode" {
+		t.Fatalf("unexpected settings theme: %q", theme)
+	}
+	if counter, _ := srv.Get("counter"); counter != "15" {
+		t.Fatalf("unexpected counter: %q", counter)
+	}
+
+	if err = s.Steps(ctx, -1); err != nil {
+		t.Fatal(err)
+	}
+	if version, _, err := s.Version(ctx); err != nil || version != 1 {
+		t.Fatalf("unexpected state: version=%d, err=%v", version, err)
+	}
+	if srv.Exists("counter") {
+		t.Fatal("counter must be deleted")
+	}
+
+	if err = s.Down(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = s.Version(ctx); !errors.Is(err, migrate.ErrNilVersion) {
+		t.Fatalf("expected %v, got %v", migrate.ErrNilVersion, err)
+	}
+	if srv.Exists("settings") || srv.Exists("features") {
+		t.Fatal("seeded keys must be deleted")
+	}
+}
+
+func TestRedis_Lock(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	srv := miniredis.RunT(t)
+
+	s, err := New(ctx, &Config{
+		RedisHost:          srv.Host(),
+		RedisPort:          srv.Port(),
+		RedisMigrationsKey: "migration_versions",
+	}, fstest.MapFS{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unlock, err := s.Lock(ctx, "go-migrate:redis")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	waitCtx, waitCancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer waitCancel()
+	if _, err = s.Lock(waitCtx, "go-migrate:redis"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+
+	if err = unlock(); err != nil {
+		t.Fatal(err)
+	}
+	if srv.Exists("migration_versions:go-migrate:redis") {
+		t.Fatal("lock must be released")
+	}
+
+	if unlock, err = s.Lock(ctx, "go-migrate:redis"); err != nil {
+		t.Fatal(err)
+	}
+	_ = unlock()
+}
+
+func TestRedis_ConnectRetries(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// reserve a free port for the server which starts after the first connection attempt
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	_ = l.Close()
+
+	srv := miniredis.NewMiniRedis()
+	defer srv.Close()
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		_ = srv.StartAddr(addr)
+	}()
+
+	host, port, _ := net.SplitHostPort(addr)
+	if _, err = New(ctx, &Config{
+		RedisHost:           host,
+		RedisPort:           port,
+		RedisConnectRetries: 10,
+		RedisConnectTimeout: 5 * time.Second,
+	}, fstest.MapFS{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDriver_Lock(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	srv := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: srv.Addr()})
+	defer func() { _ = client.Close() }()
+
+	d, err := withInstance(ctx, client, "migration_versions")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = d.Lock(); err != nil {
+		t.Fatal(err)
+	}
+	if err = d.Lock(); !errors.Is(err, database.ErrLocked) {
+		t.Fatalf("expected %v, got %v", database.ErrLocked, err)
+	}
+	if ttl := srv.TTL("migration_versions:lock"); ttl <= 0 {
+		t.Fatalf("lock must expire unless it's extended, ttl: %s", ttl)
+	}
+
+	// the expired lock is taken over by another process
+	if err = srv.Set("migration_versions:lock", "other"); err != nil {
+		t.Fatal(err)
+	}
+	if err = d.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := srv.Get("migration_versions:lock"); v != "other" {
+		t.Fatalf("lock of another owner must be kept, got %q", v)
+	}
+
+	srv.Del("migration_versions:lock")
+	if err = d.Lock(); err != nil {
+		t.Fatal(err)
+	}
+	if err = d.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	if srv.Exists("migration_versions:lock") {
+		t.Fatal("lock must be released")
+	}
+}
@@ -0,0 +1,61 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"github.com/Borislavv/go-migrate/pkg/migrate/storage"
+	"github.com/redis/go-redis/v9"
+	"io/fs"
+)
+
+var (
+	ErrFailedLoadRedisConfig     = errors.New("failed to load Redis config")
+	ErrFailedCreateInstanceRedis = errors.New("failed to create Redis instance")
+)
+
+var registration = storage.Driver[Configurator, *Redis]{
+	Name:    DriverName,
+	Load:    func(prefix string) (Configurator, error) { return LoadWithPrefix(prefix) },
+	Enabled: Configurator.IsRedisMigrationsEnabled,
+	Defaults: func(cfg Configurator) Configurator {
+		if c, ok := cfg.(*Config); ok && c != nil {
+			return storage.WithDefaults(c)
+		}
+		return cfg
+	},
+	New:       New,
+	Rename:    func(m *Redis, name string) { m.name = name },
+	ErrLoad:   ErrFailedLoadRedisConfig,
+	ErrCreate: ErrFailedCreateInstanceRedis,
+}
+
+func init() {
+	storage.Register(DriverName, Make)
+}
+
+// Make loads the instance config from env and creates the storage, it's registered as the storage.Constructor.
+func Make(ctx context.Context, instance storage.Instance) (storage.Storager, error) {
+	return registration.Make(ctx, instance)
+}
+
+// WithConfig replaces the env configured storage by the given config.
+func WithConfig(cfg Configurator, fsys fs.FS) storage.Option {
+	return WithNamedConfig(DriverName, cfg, fsys)
+}
+
+// WithNamedConfig adds the named instance of the storage configured by the given config.
+func WithNamedConfig(name string, cfg Configurator, fsys fs.FS) storage.Option {
+	return registration.WithConfig(name, cfg, fsys)
+}
+
+// WithClient replaces the env configured storage by the one which uses the existing client of the application.
+func WithClient(client redis.UniversalClient, cfg Configurator, fsys fs.FS) storage.Option {
+	return WithNamedClient(DriverName, client, cfg, fsys)
+}
+
+// WithNamedClient adds the named instance of the storage which uses the existing client of the application.
+func WithNamedClient(name string, client redis.UniversalClient, cfg Configurator, fsys fs.FS) storage.Option {
+	return registration.With(name, cfg, fsys, func(ctx context.Context, cfg Configurator, fsys fs.FS) (*Redis, error) {
+		return NewWithClient(ctx, client, cfg, fsys)
+	})
+}
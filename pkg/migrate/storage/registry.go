@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// Constructor makes a storage instance from its own (prefixed) config, it must return ErrMigrationsIsNotEnabled
+// when the instance is disabled and ErrFSWasOmitted when it's enabled but the instance FS is nil.
+type Constructor func(ctx context.Context, instance Instance) (Storager, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Constructor)
+)
+
+// Register makes a storage constructor available by the provided name (usually called from the driver init func),
+// it panics if the constructor is nil or the name is already registered.
+func Register(name string, constructor Constructor) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if constructor == nil {
+		panic("storage: Register constructor is nil for " + name)
+	}
+	if _, dup := registry[name]; dup {
+		panic("storage: Register called twice for " + name)
+	}
+	registry[name] = constructor
+}
+
+// Drivers returns sorted names of the registered storages.
+func Drivers() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func constructor(name string) (Constructor, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	c, ok := registry[name]
+	return c, ok
+}
@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"context"
+	"github.com/Borislavv/go-migrate/pkg/migrate/source"
+	"github.com/golang-migrate/migrate/v4/database"
+	migratesource "github.com/golang-migrate/migrate/v4/source"
+	"os"
+	"time"
+)
+
+// Repairer is implemented by storages which can recover the dirty state left by a failed migration.
+type Repairer interface {
+	// Repair executes the down file of the failed migration (if down is set and the file exists) and
+	// forces the clean version (database.NilVersion means no version), the repair is recorded into the history.
+	Repair(ctx context.Context, failed source.Migration, version int, down bool) error
+}
+
+// Repair executes the content (if any) through the migrate driver under its lock, forces the clean version
+// and records the repair of the failed migration into the history (if any).
+func Repair(ctx context.Context, d database.Driver, history History, failed source.Migration, content []byte, version int) error {
+	if err := d.Lock(); err != nil {
+		return err
+	}
+	defer func() { _ = d.Unlock() }()
+
+	entry := HistoryEntry{
+		Version:   failed.Version,
+		Name:      failed.Name,
+		Direction: HistoryRepair,
+		StartedAt: time.Now(),
+	}
+
+	var err error
+	if content != nil {
+		entry.Checksum = source.Checksum(content)
+		err = runMigration(d, failed.Version, migratesource.Down, content)
+	}
+	if err == nil {
+		err = d.SetVersion(version, false)
+	}
+
+	if history != nil {
+		host, _ := os.Hostname()
+		if appendErr := appendEntry(ctx, history, entry, host, err); err == nil {
+			err = appendErr
+		}
+	}
+	return err
+}
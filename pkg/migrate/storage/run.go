@@ -0,0 +1,36 @@
+package storage
+
+import (
+	"context"
+	"github.com/Borislavv/migrate/v4"
+	"time"
+)
+
+// WithTimeout limits ctx by the migrations timeout of the storage (if any).
+func WithTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// Run executes fn of the migrate instance and requests a graceful stop of the migrations once ctx is done,
+// in that case the context error is returned.
+func Run(ctx context.Context, s *migrate.Migrate, fn func() error) error {
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			s.GracefulStop <- true
+		case <-done:
+		}
+	}()
+
+	if err := fn(); err != nil {
+		return err
+	}
+
+	return ctx.Err()
+}
@@ -0,0 +1,206 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sqlHistoryDDL creates the history table in PostgreSQL, MySQL and SQLite, see SQLHistoryDDL.
+const sqlHistoryDDL = `CREATE TABLE IF NOT EXISTS %%s (
+	version     BIGINT       NOT NULL,
+	name        VARCHAR(255) NOT NULL,
+	direction   VARCHAR(8)   NOT NULL,
+	checksum    VARCHAR(64)  NOT NULL,
+	started_at  %[1]s NOT NULL,
+	finished_at %[1]s NOT NULL,
+	duration_ms BIGINT       NOT NULL,
+	host        VARCHAR(255) NOT NULL,
+	app_version VARCHAR(255) NOT NULL,
+	error       TEXT         NOT NULL
+)`
+
+// SQLHistoryDDL returns the ddl of the history table in PostgreSQL, MySQL and SQLite with the timestamp column type
+// of the dialect (e.g. TIMESTAMP(6) or DATETIME(6)), the timestamps are stored in UTC.
+func SQLHistoryDDL(timestamp string) string {
+	return fmt.Sprintf(sqlHistoryDDL, timestamp)
+}
+
+// historyTimeLayouts are accepted on reading the timestamps which are returned as text
+// (e.g. by MySQL without parseTime or by the tables created with VARCHAR timestamps by the previous versions).
+var historyTimeLayouts = []string{"2006-01-02 15:04:05.999999999Z07:00", "2006-01-02 15:04:05.999999999", time.RFC3339Nano}
+
+// SQLHistory keeps the history in the table of a database/sql storage.
+type SQLHistory struct {
+	db       *sql.DB
+	table    string
+	ddl      string
+	numbered bool
+
+	mu      sync.Mutex
+	created bool
+}
+
+// NewSQLHistory makes the history on top of the table which is created by the dialect specific ddl
+// (formatted with the table name) on the first append, reading a missing table returns no entries,
+// so the read-only calls (Status, Plan, Verify...) never run the ddl. The values are bound by ? placeholders
+// unless numbered ($1, $2...) ones are required.
+func NewSQLHistory(db *sql.DB, table, ddl string, numbered bool) *SQLHistory {
+	return &SQLHistory{db: db, table: table, ddl: ddl, numbered: numbered}
+}
+
+func (h *SQLHistory) Append(ctx context.Context, entry HistoryEntry) error {
+	if err := h.create(ctx); err != nil {
+		return err
+	}
+
+	// the transaction is required by the ClickHouse batch inserts and does no harm to others
+	tx, err := h.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	stmt, err := tx.PrepareContext(ctx, h.bind(
+		"INSERT INTO "+h.table+" (version, name, direction, checksum, started_at, finished_at, duration_ms, host, app_version, error) "+
+			"VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+	))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = stmt.Close() }()
+
+	if _, err = stmt.ExecContext(ctx,
+		int64(entry.Version),
+		entry.Name,
+		entry.Direction,
+		entry.Checksum,
+		entry.StartedAt.UTC(),
+		entry.FinishedAt.UTC(),
+		entry.Duration.Milliseconds(),
+		entry.Host,
+		entry.AppVersion,
+		entry.Error,
+	); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (h *SQLHistory) Entries(ctx context.Context) ([]HistoryEntry, error) {
+	rows, err := h.db.QueryContext(ctx,
+		"SELECT version, name, direction, checksum, started_at, finished_at, duration_ms, host, app_version, error "+
+			"FROM "+h.table+" ORDER BY started_at",
+	)
+	if err != nil {
+		if h.isMissing(err) {
+			return make([]HistoryEntry, 0), nil
+		}
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	entries := make([]HistoryEntry, 0)
+	for rows.Next() {
+		var (
+			entry               HistoryEntry
+			version, durationMs int64
+		)
+		if err = rows.Scan(
+			&version,
+			&entry.Name,
+			&entry.Direction,
+			&entry.Checksum,
+			historyTime{&entry.StartedAt},
+			historyTime{&entry.FinishedAt},
+			&durationMs,
+			&entry.Host,
+			&entry.AppVersion,
+			&entry.Error,
+		); err != nil {
+			return nil, err
+		}
+
+		entry.Version = uint(version)
+		entry.Duration = time.Duration(durationMs) * time.Millisecond
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// create runs the ddl once per history, a failed attempt is retried by the next append.
+func (h *SQLHistory) create(ctx context.Context) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.created {
+		return nil
+	}
+	if _, err := h.db.ExecContext(ctx, fmt.Sprintf(h.ddl, h.table)); err != nil {
+		return err
+	}
+	h.created = true
+	return nil
+}
+
+// isMissing reports whether the query failed because the table is not created yet, the dialects report it as
+// "no such table" (SQLite), "does not exist" (PostgreSQL) and "doesn't exist" (MySQL, ClickHouse).
+func (h *SQLHistory) isMissing(err error) bool {
+	msg := strings.ToLower(err.Error())
+	table := strings.ToLower(h.table[strings.LastIndex(h.table, ".")+1:])
+	return strings.Contains(msg, table) &&
+		(strings.Contains(msg, "no such table") || strings.Contains(msg, "does not exist") || strings.Contains(msg, "doesn't exist"))
+}
+
+// historyTime scans the timestamp returned either as time.Time or as text, see historyTimeLayouts.
+type historyTime struct {
+	t *time.Time
+}
+
+func (h historyTime) Scan(src any) error {
+	var raw string
+	switch v := src.(type) {
+	case time.Time:
+		*h.t = v.UTC()
+		return nil
+	case string:
+		raw = v
+	case []byte:
+		raw = string(v)
+	default:
+		return fmt.Errorf("unsupported history timestamp %T", src)
+	}
+
+	for _, layout := range historyTimeLayouts {
+		if t, err := time.Parse(layout, raw); err == nil {
+			*h.t = t.UTC()
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid history timestamp %q", raw)
+}
+
+// bind replaces ? placeholders by the numbered ones if required.
+func (h *SQLHistory) bind(query string) string {
+	if !h.numbered {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHistoryTime(t *testing.T) {
+	expected := time.Date(2024, 5, 1, 10, 30, 0, 123456000, time.UTC)
+	for _, src := range []any{
+		expected.In(time.FixedZone("CEST", 2*60*60)),
+		"2024-05-01 10:30:00.123456",
+		[]byte("2024-05-01 10:30:00.123456"),
+		"2024-05-01 12:30:00.123456+02:00",
+		"2024-05-01T10:30:00.123456Z",
+	} {
+		var actual time.Time
+		if err := (historyTime{&actual}).Scan(src); err != nil {
+			t.Fatal(err)
+		}
+		if !actual.Equal(expected) || actual.Location() != time.UTC {
+			t.Fatalf("unexpected time of %v: %s", src, actual)
+		}
+	}
+
+	var actual time.Time
+	if err := (historyTime{&actual}).Scan(int64(1)); err == nil {
+		t.Fatal("expected an error of the unsupported type")
+	}
+}
+
+func TestSQLHistory_IsMissing(t *testing.T) {
+	h := NewSQLHistory(nil, "app.migration_history", SQLHistoryDDL("TIMESTAMP(6)"), true)
+	for msg, missing := range map[string]bool{
+		`pq: relation "migration_history" does not exist`:               true,
+		"Error 1146: Table 'app.migration_history' doesn't exist":       true,
+		"no such table: migration_history":                              true,
+		"code: 60, message: Table app.migration_history doesn't exist.": true,
+		`pq: column "app_version" does not exist`:                       false,
+		"dial tcp 127.0.0.1:5432: connect: connection refused":          false,
+	} {
+		if actual := h.isMissing(errors.New(msg)); actual != missing {
+			t.Fatalf("expected %v for %q", missing, msg)
+		}
+	}
+}
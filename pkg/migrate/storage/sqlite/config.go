@@ -0,0 +1,74 @@
+package sqlite
+
+import (
+	"github.com/Borislavv/go-migrate/pkg/migrate/storage"
+	"time"
+)
+
+type Configurator interface {
+	IsSQLiteMigrationsEnabled() bool
+	GetSQLitePath() string
+	GetSQLiteMigrationsTable() string
+	GetSQLiteMigrationsHistoryTable() string
+	GetSQLiteConnectTimeout() time.Duration
+	GetSQLiteConnectRetries() int
+	GetSQLiteMigrationsDir() string
+	GetSQLiteMigrationsTimeout() time.Duration
+}
+
+type Config struct {
+	SQLiteMigrationsEnabled      bool          `envconfig:"SQLITE_MIGRATIONS_ENABLED" default:"false"`
+	SQLitePath                   string        `envconfig:"SQLITE_PATH"`
+	SQLiteMigrationsTable        string        `envconfig:"SQLITE_MIGRATIONS_TABLE" default:"migration_versions"`
+	SQLiteMigrationsHistoryTable string        `envconfig:"SQLITE_MIGRATIONS_HISTORY_TABLE" default:"migration_history"`
+	SQLiteConnectTimeout         time.Duration `envconfig:"SQLITE_CONNECT_TIMEOUT" default:"0"`
+	SQLiteConnectRetries         int           `envconfig:"SQLITE_CONNECT_RETRIES" default:"0"`
+	SQLiteMigrationsDir          string        `envconfig:"SQLITE_MIGRATIONS_DIR" default:"migrations"`
+	SQLiteMigrationsTimeout      time.Duration `envconfig:"SQLITE_MIGRATIONS_TIMEOUT" default:"0"`
+}
+
+// DefaultConfig returns the config with the same defaults as the env one.
+func DefaultConfig() *Config {
+	return storage.SetDefaults(new(Config))
+}
+
+func Load() (*Config, error) {
+	return LoadWithPrefix("")
+}
+
+// LoadWithPrefix loads the config of a named storage instance, e.g. BILLING_SQLITE_PATH for the "BILLING" prefix.
+func LoadWithPrefix(prefix string) (*Config, error) {
+	return storage.LoadEnv[Config](prefix)
+}
+
+func (c *Config) IsSQLiteMigrationsEnabled() bool {
+	return c.SQLiteMigrationsEnabled
+}
+
+func (c *Config) GetSQLitePath() string {
+	return c.SQLitePath
+}
+
+func (c *Config) GetSQLiteMigrationsTable() string {
+	return c.SQLiteMigrationsTable
+}
+
+func (c *Config) GetSQLiteMigrationsHistoryTable() string {
+	return c.SQLiteMigrationsHistoryTable
+}
+
+func (c *Config) GetSQLiteConnectTimeout() time.Duration {
+	return c.SQLiteConnectTimeout
+}
+
+func (c *Config) GetSQLiteConnectRetries() int {
+	return c.SQLiteConnectRetries
+}
+
+func (c *Config) GetSQLiteMigrationsDir() string {
+	return c.SQLiteMigrationsDir
+}
+
+func (c *Config) GetSQLiteMigrationsTimeout() time.Duration {
+	return c.SQLiteMigrationsTimeout
+}
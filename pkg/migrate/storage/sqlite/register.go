@@ -0,0 +1,61 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/Borislavv/go-migrate/pkg/migrate/storage"
+	"io/fs"
+)
+
+var (
+	ErrFailedLoadSQLiteConfig     = errors.New("failed to load SQLite config")
+	ErrFailedCreateInstanceSQLite = errors.New("failed to create SQLite instance")
+)
+
+var registration = storage.Driver[Configurator, *SQLite]{
+	Name:    DriverName,
+	Load:    func(prefix string) (Configurator, error) { return LoadWithPrefix(prefix) },
+	Enabled: Configurator.IsSQLiteMigrationsEnabled,
+	Defaults: func(cfg Configurator) Configurator {
+		if c, ok := cfg.(*Config); ok && c != nil {
+			return storage.WithDefaults(c)
+		}
+		return cfg
+	},
+	New:       New,
+	Rename:    func(m *SQLite, name string) { m.name = name },
+	ErrLoad:   ErrFailedLoadSQLiteConfig,
+	ErrCreate: ErrFailedCreateInstanceSQLite,
+}
+
+func init() {
+	storage.Register(DriverName, Make)
+}
+
+// Make loads the instance config from env and creates the storage, it's registered as the storage.Constructor.
+func Make(ctx context.Context, instance storage.Instance) (storage.Storager, error) {
+	return registration.Make(ctx, instance)
+}
+
+// WithConfig replaces the env configured storage by the given config.
+func WithConfig(cfg Configurator, fsys fs.FS) storage.Option {
+	return WithNamedConfig(DriverName, cfg, fsys)
+}
+
+// WithNamedConfig adds the named instance of the storage configured by the given config.
+func WithNamedConfig(name string, cfg Configurator, fsys fs.FS) storage.Option {
+	return registration.WithConfig(name, cfg, fsys)
+}
+
+// WithDB replaces the env configured storage by the one which uses the existing connection pool of the application.
+func WithDB(db *sql.DB, cfg Configurator, fsys fs.FS) storage.Option {
+	return WithNamedDB(DriverName, db, cfg, fsys)
+}
+
+// WithNamedDB adds the named instance of the storage which uses the existing connection pool of the application.
+func WithNamedDB(name string, db *sql.DB, cfg Configurator, fsys fs.FS) storage.Option {
+	return registration.With(name, cfg, fsys, func(ctx context.Context, cfg Configurator, fsys fs.FS) (*SQLite, error) {
+		return NewWithDB(ctx, db, cfg, fsys)
+	})
+}
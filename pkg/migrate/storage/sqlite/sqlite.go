@@ -0,0 +1,264 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/Borislavv/go-migrate/pkg/migrate/source"
+	"github.com/Borislavv/go-migrate/pkg/migrate/storage"
+	"github.com/Borislavv/migrate/v4"
+	"github.com/Borislavv/migrate/v4/database/sqlite"
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"io/fs"
+)
+
+const (
+	DriverName = "sqlite"
+	// MigrationsExt is the extension of the migration files.
+	MigrationsExt = "sql"
+)
+
+type SQLite struct {
+	name    string
+	db      *sql.DB
+	cfg     Configurator
+	fs      fs.FS
+	history storage.History
+}
+
+func New(ctx context.Context, cfg Configurator, fsys fs.FS) (*SQLite, error) {
+	if cfg.GetSQLitePath() == "" {
+		return nil, errors.New("SQLite database path is empty")
+	}
+
+	db, err := sql.Open(DriverName, cfg.GetSQLitePath())
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		<-ctx.Done()
+		_ = db.Close()
+	}()
+
+	return NewWithDB(ctx, db, cfg, fsys)
+}
+
+// NewWithDB makes the storage on top of the existing connection pool, which is owned (and closed) by the caller.
+func NewWithDB(ctx context.Context, db *sql.DB, cfg Configurator, fsys fs.FS) (*SQLite, error) {
+	if err := storage.Connect(ctx, cfg.GetSQLiteConnectRetries(), cfg.GetSQLiteConnectTimeout(), db.PingContext); err != nil {
+		return nil, err
+	}
+
+	var h storage.History
+	if table := cfg.GetSQLiteMigrationsHistoryTable(); storage.IsHistoryEnabled(table) {
+		h = storage.NewSQLHistory(db, table, storage.SQLHistoryDDL("DATETIME"), false)
+	}
+
+	return &SQLite{
+		name:    DriverName,
+		db:      db,
+		cfg:     cfg,
+		fs:      fsys,
+		history: h,
+	}, nil
+}
+
+func (m *SQLite) Name() string {
+	return m.name
+}
+
+func (m *SQLite) Up(ctx context.Context) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	s, err := m.migrate(ctx)
+	if err != nil {
+		return err
+	}
+
+	return storage.Run(ctx, s, s.Up)
+}
+
+func (m *SQLite) Down(ctx context.Context) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	s, err := m.migrate(ctx)
+	if err != nil {
+		return err
+	}
+
+	return storage.Run(ctx, s, s.Down)
+}
+
+func (m *SQLite) Migrate(ctx context.Context, version uint) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	s, err := m.migrate(ctx)
+	if err != nil {
+		return err
+	}
+
+	return storage.Run(ctx, s, func() error { return s.Migrate(version) })
+}
+
+func (m *SQLite) Steps(ctx context.Context, n int) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	s, err := m.migrate(ctx)
+	if err != nil {
+		return err
+	}
+
+	return storage.Run(ctx, s, func() error { return s.Steps(n) })
+}
+
+func (m *SQLite) Force(ctx context.Context, n int) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	s, err := m.migrate(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err = s.Force(n); err != nil {
+		return err
+	}
+
+	return ctx.Err()
+}
+
+func (m *SQLite) Version(ctx context.Context) (version uint, dirty bool, err error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	s, err := m.migrate(ctx)
+	if err != nil {
+		return 0, true, err
+	}
+
+	return s.Version()
+}
+
+// Migrations returns all migrations available in the migrations fs and the registered Go ones ordered by version.
+func (m *SQLite) Migrations() ([]source.Migration, error) {
+	migrations, err := source.List(m.fs, m.cfg.GetSQLiteMigrationsDir())
+	if err != nil {
+		return nil, err
+	}
+
+	return storage.WithGoMigrations(m.name, migrations)
+}
+
+// ReadMigration returns the content of the migration file by its path in the migrations fs (or of the Go migration).
+func (m *SQLite) ReadMigration(path string) ([]byte, error) {
+	if content, ok := storage.ReadGoMigration(m.name, path); ok {
+		return content, nil
+	}
+	return fs.ReadFile(m.fs, path)
+}
+
+// History returns the executed migrations recorded into SQLITE_MIGRATIONS_HISTORY_TABLE,
+// the history is disabled when it's empty or storage.HistoryDisabled, see storage.Historian.
+func (m *SQLite) History(ctx context.Context) ([]storage.HistoryEntry, error) {
+	if m.history == nil {
+		return nil, storage.ErrHistoryIsNotEnabled
+	}
+
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	return m.history.Entries(ctx)
+}
+
+// Apply executes the up file of the migration without moving the version, see storage.Applier.
+func (m *SQLite) Apply(ctx context.Context, migration source.Migration) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	content, err := m.ReadMigration(migration.Up)
+	if err != nil {
+		return err
+	}
+
+	d, err := m.driver(ctx)
+	if err != nil {
+		return err
+	}
+
+	return storage.Apply(ctx, d, m.history, migration, content)
+}
+
+// Repair executes the down file of the failed migration (if requested) and forces the clean version,
+// see storage.Repairer.
+func (m *SQLite) Repair(ctx context.Context, failed source.Migration, version int, down bool) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	var content []byte
+	if down && failed.Down != "" {
+		var err error
+		if content, err = m.ReadMigration(failed.Down); err != nil {
+			return err
+		}
+	}
+
+	d, err := m.driver(ctx)
+	if err != nil {
+		return err
+	}
+
+	return storage.Repair(ctx, d, m.history, failed, content, version)
+}
+
+// withTimeout limits ctx by the configured migrations timeout (if any).
+func (m *SQLite) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return storage.WithTimeout(ctx, m.cfg.GetSQLiteMigrationsTimeout())
+}
+
+func (m *SQLite) migrate(ctx context.Context) (*migrate.Migrate, error) {
+	migrations, err := m.Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := m.driver(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	src, err := iofs.New(m.fs, m.cfg.GetSQLiteMigrationsDir())
+	if err != nil {
+		return nil, fmt.Errorf("could not open SQLite migrations fs: %w", err)
+	}
+
+	s, err := migrate.NewWithInstance("iofs", storage.GoSource(src, m.name, migrations), DriverName, storage.Record(ctx, d, m.history, migrations))
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// driver makes the migrate database driver, it must not be closed as it would close the shared pool.
+func (m *SQLite) driver(ctx context.Context) (database.Driver, error) {
+	if m.db == nil {
+		return nil, errors.New("the underlying database pointer is not initialized, you need to call the 'New' method first")
+	}
+
+	d, err := sqlite.WithInstance(m.db, &sqlite.Config{
+		DatabaseName:    m.cfg.GetSQLitePath(),
+		MigrationsTable: m.cfg.GetSQLiteMigrationsTable(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return storage.GoDriver(ctx, d, m.name, storage.TxExecutor(m.db)), nil
+}
@@ -0,0 +1,360 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/Borislavv/go-logger/pkg/logger"
+	loggerenum "github.com/Borislavv/go-logger/pkg/logger/enum"
+	"github.com/Borislavv/go-migrate/pkg/migrate/source"
+	"github.com/Borislavv/go-migrate/pkg/migrate/storage"
+	"github.com/Borislavv/migrate/v4"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestSQLite(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	fsys := fstest.MapFS{
+		"migrations/1_create_users.up.sql":   {Data: []byte("CREATE TABLE users (id INTEGER PRIMARY KEY);")},
+		"migrations/1_create_users.down.sql": {Data: []byte("DROP TABLE users;")},
+		"migrations/2_add_email.up.sql":      {Data: []byte("ALTER TABLE users ADD COLUMN email TEXT;")},
+		"migrations/2_add_email.down.sql":    {Data: []byte("ALTER TABLE users DROP COLUMN email;")},
+	}
+
+	s, err := New(ctx, &Config{
+		SQLitePath:            filepath.Join(t.TempDir(), "test.db"),
+		SQLiteMigrationsTable: "migration_versions",
+		SQLiteMigrationsDir:   "migrations",
+	}, fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err = s.Version(ctx); !errors.Is(err, migrate.ErrNilVersion) {
+		t.Fatalf("expected %v, got %v", migrate.ErrNilVersion, err)
+	}
+
+	if err = s.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if version, dirty, err := s.Version(ctx); err != nil || version != 2 || dirty {
+		t.Fatalf("unexpected state: version=%d, dirty=%t, err=%v", version, dirty, err)
+	}
+
+	if err = s.Steps(ctx, -1); err != nil {
+		t.Fatal(err)
+	}
+	if version, _, err := s.Version(ctx); err != nil || version != 1 {
+		t.Fatalf("unexpected state: version=%d, err=%v", version, err)
+	}
+
+	if err = s.Migrate(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Up(ctx); !errors.Is(err, migrate.ErrNoChange) {
+		t.Fatalf("expected %v, got %v", migrate.ErrNoChange, err)
+	}
+
+	if err = s.Down(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = s.Version(ctx); !errors.Is(err, migrate.ErrNilVersion) {
+		t.Fatalf("expected %v, got %v", migrate.ErrNilVersion, err)
+	}
+}
+
+func TestSQLite_WithDB(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	out, cancelOutput, err := logger.NewOutput(loggerenum.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancelOutput()
+
+	lgr, cancelLogger, err := logger.NewLogrus(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancelLogger()
+
+	db, err := sql.Open(DriverName, filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+
+	fsys := fstest.MapFS{
+		"migrations/1_create_users.up.sql":   {Data: []byte("CREATE TABLE users (id INTEGER PRIMARY KEY);")},
+		"migrations/1_create_users.down.sql": {Data: []byte("DROP TABLE users;")},
+	}
+
+	storages, err := storage.NewFactory(lgr, storage.WithoutEnv(), WithDB(db, &Config{
+		SQLiteMigrationsEnabled: true,
+		SQLiteMigrationsTable:   "migration_versions",
+		SQLiteMigrationsDir:     "migrations",
+	}, fsys)).Make(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(storages) != 1 || storages[0].Name() != DriverName {
+		t.Fatalf("unexpected storages: %v", storages)
+	}
+
+	if err = storages[0].Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// the application pool must stay usable and see the migrated schema
+	if _, err = db.ExecContext(ctx, "INSERT INTO users (id) VALUES (1)"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSQLite_History(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	fsys := fstest.MapFS{
+		"migrations/1_create_users.up.sql":   {Data: []byte("CREATE TABLE users (id INTEGER PRIMARY KEY);")},
+		"migrations/1_create_users.down.sql": {Data: []byte("DROP TABLE users;")},
+		"migrations/2_broken.up.sql":         {Data: []byte("ALTER TABLE missing ADD COLUMN email TEXT;")},
+	}
+
+	s, err := New(ctx, &Config{
+		SQLitePath:                   filepath.Join(t.TempDir(), "test.db"),
+		SQLiteMigrationsTable:        "migration_versions",
+		SQLiteMigrationsHistoryTable: "migration_history",
+		SQLiteMigrationsDir:          "migrations",
+	}, fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// reading the history doesn't create the table
+	if entries, err := s.History(ctx); err != nil || len(entries) != 0 {
+		t.Fatalf("unexpected history: %+v, %v", entries, err)
+	}
+	var tables int
+	if err = s.db.QueryRowContext(ctx, "SELECT count(*) FROM sqlite_master WHERE name = 'migration_history'").Scan(&tables); err != nil || tables != 0 {
+		t.Fatalf("the history table must not be created by reading: %d, %v", tables, err)
+	}
+
+	if err = s.Migrate(storage.WithAppVersion(ctx, "v1.2.3"), 1); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Steps(ctx, -1); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Up(ctx); err == nil {
+		t.Fatal("expected an error of the broken migration")
+	}
+
+	entries, err := s.History(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 4 {
+		t.Fatalf("expected 4 entries, got %+v", entries)
+	}
+
+	expected := []struct {
+		version   uint
+		name      string
+		direction string
+		content   string
+		failed    bool
+	}{
+		{version: 1, name: "create_users", direction: storage.HistoryUp, content: "CREATE TABLE users (id INTEGER PRIMARY KEY);"},
+		{version: 1, name: "create_users", direction: storage.HistoryDown, content: "DROP TABLE users;"},
+		{version: 1, name: "create_users", direction: storage.HistoryUp, content: "CREATE TABLE users (id INTEGER PRIMARY KEY);"},
+		{version: 2, name: "broken", direction: storage.HistoryUp, content: "ALTER TABLE missing ADD COLUMN email TEXT;", failed: true},
+	}
+	for i, e := range expected {
+		entry := entries[i]
+		if entry.Version != e.version || entry.Name != e.name || entry.Direction != e.direction ||
+			entry.Checksum != source.Checksum([]byte(e.content)) || (entry.Error != "") != e.failed {
+			t.Fatalf("unexpected entry %d: %+v", i, entry)
+		}
+		if entry.StartedAt.IsZero() || entry.FinishedAt.Before(entry.StartedAt) || entry.Host == "" {
+			t.Fatalf("unexpected entry %d: %+v", i, entry)
+		}
+	}
+	if time.Since(entries[0].StartedAt) > time.Minute || entries[0].StartedAt.Location() != time.UTC {
+		t.Fatalf("unexpected timestamp: %s", entries[0].StartedAt)
+	}
+	if entries[0].AppVersion != "v1.2.3" || entries[1].AppVersion != "" {
+		t.Fatalf("unexpected app versions: %q, %q", entries[0].AppVersion, entries[1].AppVersion)
+	}
+}
+
+func TestSQLite_Apply(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	fsys := fstest.MapFS{
+		"migrations/1_create_users.up.sql":  {Data: []byte("CREATE TABLE users (id INTEGER PRIMARY KEY);")},
+		"migrations/3_create_orders.up.sql": {Data: []byte("CREATE TABLE orders (id INTEGER PRIMARY KEY);")},
+	}
+
+	s, err := New(ctx, &Config{
+		SQLitePath:                   filepath.Join(t.TempDir(), "test.db"),
+		SQLiteMigrationsTable:        "migration_versions",
+		SQLiteMigrationsHistoryTable: "migration_history",
+		SQLiteMigrationsDir:          "migrations",
+	}, fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = s.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// merged from another branch after 3 was applied
+	fsys["migrations/2_add_email.up.sql"] = &fstest.MapFile{Data: []byte("ALTER TABLE users ADD COLUMN email TEXT;")}
+	if err = s.Apply(ctx, source.Migration{Version: 2, Name: "add_email", Up: "migrations/2_add_email.up.sql"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if version, dirty, err := s.Version(ctx); err != nil || version != 3 || dirty {
+		t.Fatalf("unexpected state: version=%d, dirty=%t, err=%v", version, dirty, err)
+	}
+	if _, err = s.db.ExecContext(ctx, "INSERT INTO users (id, email) VALUES (1, 'user@example.com')"); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := s.History(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last := entries[len(entries)-1]; len(entries) != 3 || last.Version != 2 || last.Direction != storage.HistoryUp || last.Error != "" {
+		t.Fatalf("unexpected history: %+v", entries)
+	}
+}
+
+func TestSQLite_Repair(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	fsys := fstest.MapFS{
+		"migrations/1_create_users.up.sql":    {Data: []byte("CREATE TABLE users (id INTEGER PRIMARY KEY);")},
+		"migrations/1_create_users.down.sql":  {Data: []byte("DROP TABLE users;")},
+		"migrations/2_create_orders.up.sql":   {Data: []byte("CREATE TABLE orders (id INTEGER PRIMARY KEY); INSERT INTO missing VALUES (1);")},
+		"migrations/2_create_orders.down.sql": {Data: []byte("DROP TABLE IF EXISTS orders;")},
+	}
+
+	s, err := New(ctx, &Config{
+		SQLitePath:                   filepath.Join(t.TempDir(), "test.db"),
+		SQLiteMigrationsTable:        "migration_versions",
+		SQLiteMigrationsHistoryTable: "migration_history",
+		SQLiteMigrationsDir:          "migrations",
+	}, fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = s.Up(ctx); err == nil {
+		t.Fatal("expected the second migration to fail")
+	}
+	if version, dirty, err := s.Version(ctx); err != nil || version != 2 || !dirty {
+		t.Fatalf("unexpected state: version=%d, dirty=%t, err=%v", version, dirty, err)
+	}
+
+	failed := source.Migration{Version: 2, Name: "create_orders", Down: "migrations/2_create_orders.down.sql"}
+	if err = s.Repair(ctx, failed, 1, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if version, dirty, err := s.Version(ctx); err != nil || version != 1 || dirty {
+		t.Fatalf("unexpected state: version=%d, dirty=%t, err=%v", version, dirty, err)
+	}
+
+	entries, err := s.History(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last := entries[len(entries)-1]; len(entries) != 3 || last.Version != 2 ||
+		last.Direction != storage.HistoryRepair || last.Checksum == "" || last.Error != "" {
+		t.Fatalf("unexpected history: %+v", entries)
+	}
+}
+
+func TestSQLite_GoMigrations(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	storage.RegisterGo("sqlite-go", 2,
+		func(ctx context.Context, db any) error {
+			_, err := db.(*sql.Tx).ExecContext(ctx, "INSERT INTO users (id, email) VALUES (1, 'USER@EXAMPLE.COM')")
+			return err
+		},
+		func(ctx context.Context, db any) error {
+			_, err := db.(*sql.Tx).ExecContext(ctx, "DELETE FROM users")
+			return err
+		},
+	)
+
+	fsys := fstest.MapFS{
+		"migrations/1_create_users.up.sql":   {Data: []byte("CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT);")},
+		"migrations/1_create_users.down.sql": {Data: []byte("DROP TABLE users;")},
+		"migrations/3_lower_emails.up.sql":   {Data: []byte("UPDATE users SET email = lower(email);")},
+	}
+
+	s, err := New(ctx, &Config{
+		SQLitePath:                   filepath.Join(t.TempDir(), "test.db"),
+		SQLiteMigrationsTable:        "migration_versions",
+		SQLiteMigrationsHistoryTable: "migration_history",
+		SQLiteMigrationsDir:          "migrations",
+	}, fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.name = "sqlite-go"
+
+	migrations, err := s.Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 3 || migrations[1].Version != 2 || migrations[1].Name != storage.GoMigrationName {
+		t.Fatalf("unexpected migrations: %+v", migrations)
+	}
+
+	if err = s.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if version, dirty, err := s.Version(ctx); err != nil || version != 3 || dirty {
+		t.Fatalf("unexpected state: version=%d, dirty=%t, err=%v", version, dirty, err)
+	}
+
+	var email string
+	if err = s.db.QueryRowContext(ctx, "SELECT email FROM users WHERE id = 1").Scan(&email); err != nil {
+		t.Fatal(err)
+	}
+	if email != "user@example.com" {
+		t.Fatalf("expected the go migration to run before the third one, got email: %s", email)
+	}
+
+	// 3 has no down file, 2 is rolled back by the go func
+	if err = s.Migrate(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	var count int
+	if err = s.db.QueryRowContext(ctx, "SELECT count(*) FROM users").Scan(&count); err != nil || count != 0 {
+		t.Fatalf("expected the go down migration to clean users, count=%d, err=%v", count, err)
+	}
+
+	entries, err := s.History(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 5 || entries[1].Version != 2 || entries[1].Name != storage.GoMigrationName || entries[1].Error != "" {
+		t.Fatalf("unexpected history: %+v", entries)
+	}
+}
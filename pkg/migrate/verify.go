@@ -0,0 +1,156 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Borislavv/go-logger/pkg/logger"
+	"github.com/Borislavv/go-migrate/pkg/migrate/source"
+	"github.com/Borislavv/go-migrate/pkg/migrate/storage"
+)
+
+var (
+	ErrUnknownChecksumPolicy = errors.New("unknown checksum policy")
+	ErrChecksumMismatch      = errors.New("applied migrations were changed")
+)
+
+// ChecksumPolicy defines what happens when an applied migration file no longer matches the recorded checksum.
+type ChecksumPolicy string
+
+const (
+	// ChecksumFail rejects Up and Verify.
+	ChecksumFail ChecksumPolicy = "fail"
+	// ChecksumWarn logs a warning for each changed migration.
+	ChecksumWarn ChecksumPolicy = "warn"
+	// ChecksumIgnore skips the verification on Up, Verify still reports the changed migrations.
+	ChecksumIgnore ChecksumPolicy = "ignore"
+)
+
+// ChecksumMismatch describes an applied migration which file was changed since it was applied.
+type ChecksumMismatch struct {
+	Storage string `json:"storage"`
+	Version uint   `json:"version"`
+	Name    string `json:"name"`
+	File    string `json:"file"`
+	// Applied is the checksum recorded into the history when the migration was applied.
+	Applied string `json:"applied"`
+	Actual  string `json:"actual"`
+}
+
+// SetChecksumPolicy overrides the checksum policy loaded from env (MIGRATIONS_CHECKSUM_POLICY).
+func (m *Migrate) SetChecksumPolicy(policy ChecksumPolicy) error {
+	switch policy {
+	case ChecksumFail, ChecksumWarn, ChecksumIgnore:
+		m.cfg.ChecksumPolicy = policy
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownChecksumPolicy, policy)
+	}
+}
+
+// Verify compares applied migrations of each storage with the checksums recorded into the history and returns
+// the changed ones, ErrChecksumMismatch is returned along with them under the ChecksumFail policy.
+// Storages without history, migrations applied before the history was enabled and the Go migrations (their content
+// is the path, see storage.IsGoMigration) are skipped.
+func (m *Migrate) Verify(ctx context.Context) ([]ChecksumMismatch, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	return m.verify(ctx, "Verify", m.storages...)
+}
+
+func (m *Migrate) verify(ctx context.Context, action string, storages ...storage.Storager) ([]ChecksumMismatch, error) {
+	var mismatches []ChecksumMismatch
+	for _, s := range storages {
+		found, err := m.mismatches(ctx, s)
+		if err != nil {
+			return nil, m.logger.Fatal(ctx, errors.New("migrations: [storage: "+s.Name()+", action: "+action+"]: error occurred while verifying checksums"), logger.Fields{
+				"err":     err.Error(),
+				"storage": s.Name(),
+			})
+		}
+		mismatches = append(mismatches, found...)
+	}
+
+	if len(mismatches) == 0 || m.cfg.ChecksumPolicy == ChecksumIgnore {
+		return mismatches, nil
+	}
+
+	for _, mismatch := range mismatches {
+		msg := "migrations: [storage: " + mismatch.Storage + ", action: " + action + "]: applied migration was changed"
+		fields := logger.Fields{
+			"storage": mismatch.Storage,
+			"version": mismatch.Version,
+			"file":    mismatch.File,
+			"applied": mismatch.Applied,
+			"actual":  mismatch.Actual,
+		}
+		if m.cfg.ChecksumPolicy == ChecksumFail {
+			m.logger.ErrorMsg(ctx, msg, fields)
+		} else {
+			m.logger.WarningMsg(ctx, msg, fields)
+		}
+	}
+
+	if m.cfg.ChecksumPolicy == ChecksumFail {
+		return mismatches, fmt.Errorf("%w: %d migration(s), see MIGRATIONS_CHECKSUM_POLICY", ErrChecksumMismatch, len(mismatches))
+	}
+	return mismatches, nil
+}
+
+// mismatches compares the applied migrations of the storage with the last successful up recorded for each of them.
+func (m *Migrate) mismatches(ctx context.Context, s storage.Storager) ([]ChecksumMismatch, error) {
+	historian, ok := s.(storage.Historian)
+	if !ok {
+		return nil, nil
+	}
+
+	entries, err := historian.History(ctx)
+	if err != nil {
+		if errors.Is(err, storage.ErrHistoryIsNotEnabled) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	applied := make(map[uint]string, len(entries))
+	for _, entry := range entries {
+		if entry.Direction == storage.HistoryUp && entry.Error == "" && entry.Checksum != "" {
+			applied[entry.Version] = entry.Checksum
+		}
+	}
+	if len(applied) == 0 {
+		return nil, nil
+	}
+
+	status, err := m.status(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+
+	var mismatches []ChecksumMismatch
+	for _, migration := range status.Applied {
+		checksum, ok := applied[migration.Version]
+		if !ok || migration.Up == "" || storage.IsGoMigration(s.Name(), migration.Version) {
+			// the content of a Go migration is its path, the code itself can't be verified
+			continue
+		}
+
+		content, err := s.ReadMigration(migration.Up)
+		if err != nil {
+			return nil, err
+		}
+		if actual := source.Checksum(content); actual != checksum {
+			mismatches = append(mismatches, ChecksumMismatch{
+				Storage: s.Name(),
+				Version: migration.Version,
+				Name:    migration.Name,
+				File:    migration.Up,
+				Applied: checksum,
+				Actual:  actual,
+			})
+		}
+	}
+
+	return mismatches, nil
+}